@@ -0,0 +1,329 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	formatZip     = "zip"
+	formatGzip    = "gzip"
+	formatTar     = "tar"
+	formatText    = "text"
+	formatUnknown = "unknown"
+)
+
+// sniffLen covers the tar magic at offset 257.
+const sniffLen = 512
+
+// extractLimits guard extraction against zip bombs. Zero MaxBytes or
+// MaxRatio means no limit.
+type extractLimits struct {
+	MaxBytes int64   // uncompressed bytes per archive
+	MaxRatio float64 // uncompressed bytes per compressed byte
+	MaxDepth int     // levels of archives nested inside the downloaded one
+}
+
+// defaultExtractLimits are the defaults of -max-extract-mb, -max-extract-ratio
+// and -archive-depth.
+var defaultExtractLimits = extractLimits{MaxBytes: 20480 << 20, MaxRatio: 200, MaxDepth: 3}
+
+// extractBudget counts the bytes read from one archive against its limit.
+type extractBudget struct {
+	limit   int64
+	written int64
+}
+
+func newExtractBudget(limits extractLimits, archiveSize int64) *extractBudget {
+	b := &extractBudget{limit: limits.MaxBytes}
+	if limits.MaxRatio > 0 {
+		byRatio := int64(limits.MaxRatio * float64(archiveSize))
+		if b.limit == 0 || byRatio < b.limit {
+			b.limit = byRatio
+		}
+	}
+	return b
+}
+
+// detectFormat tells the archive format from the first bytes of a file.
+func detectFormat(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return formatZip
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return formatGzip
+	case len(head) >= 262 && string(head[257:262]) == "ustar":
+		return formatTar
+	case len(head) > 0 && bytes.IndexByte(head, 0) < 0:
+		return formatText
+	}
+	return formatUnknown
+}
+
+// walkArchive streams every text file of the dump src to fn whatever its
+// extension: zip and tar members, the contents of gzip and .tar.gz, or src
+// itself when it is a plain text dump, named <name>.txt. Archives inside the
+// dump are opened up to limits.MaxDepth levels deep, their members named
+// <archive>/<member>. Only members matching include (all when empty) and
+// none of exclude reach fn, binary members are skipped. Members with unsafe
+// names are refused and reading fails once the contents outgrow limits.
+func walkArchive(src string, limits extractLimits, include, exclude []string, fn func(name string, r io.Reader) error) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	w := &archiveWalker{
+		budget:  newExtractBudget(limits, info.Size()),
+		depth:   limits.MaxDepth,
+		include: include,
+		exclude: exclude,
+		fn:      fn,
+	}
+
+	br := bufio.NewReaderSize(f, sniffLen)
+	head, _ := br.Peek(sniffLen)
+
+	switch detectFormat(head) {
+	case formatZip:
+		return w.walkZip(src, "", 0)
+	case formatGzip:
+		return w.walkGzip(br, filepath.Base(src), "", 0)
+	case formatTar:
+		return w.walkTar(br, "", 0)
+	case formatText:
+		// a plain dump is no member, include and exclude don't apply
+		name := strings.TrimSuffix(filepath.Base(src), filepath.Ext(src)) + ".txt"
+		return fn(name, w.budget.reader(br, name))
+	}
+	return fmt.Errorf("unknown archive format: %s", src)
+}
+
+// archiveWalker carries the state shared by all levels of one dump.
+type archiveWalker struct {
+	budget  *extractBudget
+	depth   int
+	include []string
+	exclude []string
+	fn      func(name string, r io.Reader) error
+}
+
+// member hands a text member to fn and opens a nested archive, level is the
+// number of archives around r.
+func (w *archiveWalker) member(name string, r io.Reader, level int) error {
+	br := bufio.NewReaderSize(r, sniffLen)
+	head, _ := br.Peek(sniffLen)
+
+	format := detectFormat(head)
+	switch format {
+	case formatText:
+		if !matchMember(w.include, name, true) || matchMember(w.exclude, name, false) {
+			log.Infof("Skipping Member: %s", name)
+			return nil
+		}
+		return w.fn(name, w.budget.reader(br, name))
+	case formatUnknown:
+		log.Infof("Skipping Binary Member: %s", name)
+		return nil
+	}
+
+	if level > w.depth {
+		log.Warnf("Skipping Nested Archive Deeper Than %d Levels: %s", w.depth, name)
+		return nil
+	}
+	prefix := strings.TrimSuffix(name, path.Ext(name))
+	switch format {
+	case formatZip:
+		// zip needs random access, keep the nested archive in a temp file.
+		// Only its members count against the budget, the copy may just not
+		// be larger than what is left of it.
+		tmp, err := ioutil.TempFile("", "nested-*.zip")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		var src io.Reader = br
+		left := w.budget.limit - w.budget.written
+		if w.budget.limit > 0 {
+			src = io.LimitReader(br, left+1)
+		}
+		n, err := io.Copy(tmp, src)
+		tmp.Close()
+		if err != nil {
+			return err
+		}
+		if w.budget.limit > 0 && n > left {
+			return fmt.Errorf("extracted size exceeds limit of %d bytes at %s", w.budget.limit, name)
+		}
+		return w.walkZip(tmp.Name(), prefix, level)
+	case formatGzip:
+		return w.walkGzip(br, name, prefix, level)
+	}
+	return w.walkTar(br, prefix, level)
+}
+
+func (w *archiveWalker) walkZip(src, prefix string, level int) error {
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	// the declared sizes may lie, they are checked again while reading
+	var declared uint64
+	for _, zf := range zr.File {
+		if err := checkMemberName(zf.Name); err != nil {
+			return err
+		}
+		declared += zf.UncompressedSize64
+	}
+	if w.budget.limit > 0 {
+		left := w.budget.limit - w.budget.written
+		if left < 0 || declared > uint64(left) {
+			return fmt.Errorf("extracted size exceeds limit of %d bytes", w.budget.limit)
+		}
+	}
+
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return err
+		}
+		err = w.member(path.Join(prefix, strings.ReplaceAll(zf.Name, `\`, "/")), rc, level+1)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkGzip opens a gzip stream, a tar inside it belongs to the same level.
+func (w *archiveWalker) walkGzip(r io.Reader, name, prefix string, level int) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	br := bufio.NewReaderSize(gz, sniffLen)
+	head, _ := br.Peek(sniffLen)
+	if detectFormat(head) == formatTar {
+		return w.walkTar(br, prefix, level)
+	}
+
+	member := gz.Name
+	if member == "" {
+		member = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	}
+	if filepath.Ext(member) != ".txt" && detectFormat(head) == formatText {
+		member += ".txt"
+	}
+	return w.member(path.Join(path.Dir(prefix), path.Base(member)), br, level+1)
+}
+
+func (w *archiveWalker) walkTar(r io.Reader, prefix string, level int) error {
+	tr := tar.NewReader(r)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := checkMemberName(hdr.Name); err != nil {
+			return err
+		}
+		if err := w.member(path.Join(prefix, hdr.Name), tr, level+1); err != nil {
+			return err
+		}
+	}
+}
+
+// matchMember reports whether a glob of patterns matches the member name or
+// its base name, or empty when there are no patterns.
+func matchMember(patterns []string, name string, empty bool) bool {
+	if len(patterns) == 0 {
+		return empty
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, path.Base(name)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// budgetReader fails a read once the archive has produced more than its budget.
+type budgetReader struct {
+	r      io.Reader
+	budget *extractBudget
+	name   string
+}
+
+func (b *extractBudget) reader(r io.Reader, name string) io.Reader {
+	return &budgetReader{r: r, budget: b, name: name}
+}
+
+func (br *budgetReader) Read(p []byte) (int, error) {
+	n, err := br.r.Read(p)
+	br.budget.written += int64(n)
+	if br.budget.limit > 0 && br.budget.written > br.budget.limit {
+		return n, fmt.Errorf("extracted size exceeds limit of %d bytes at %s", br.budget.limit, br.name)
+	}
+	return n, err
+}
+
+// createMember creates the file for the archive member name below dest.
+func createMember(dest, name string) (*os.File, error) {
+	if err := checkMemberName(name); err != nil {
+		return nil, err
+	}
+	target := filepath.Join(dest, filepath.FromSlash(strings.ReplaceAll(name, `\`, "/")))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return nil, err
+	}
+	return os.Create(target)
+}
+
+// checkMemberName refuses member names that are absolute or climb out of
+// the destination with "..".
+func checkMemberName(name string) error {
+	clean := filepath.FromSlash(strings.ReplaceAll(name, `\`, "/"))
+	if filepath.IsAbs(clean) || filepath.VolumeName(clean) != "" || strings.HasPrefix(clean, string(filepath.Separator)) {
+		return fmt.Errorf("refusing absolute member path %q", name)
+	}
+	rel := filepath.Clean(clean)
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("refusing member path outside destination %q", name)
+	}
+	return nil
+}
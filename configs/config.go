@@ -2,19 +2,29 @@ package configs
 
 import (
 	"bytes"
+	"encoding/csv"
 	"io"
 )
 
 type Config struct {
-	FtpName      string `json:"ftpname"`
-	RemoteServer string `json:"servername"`
-	RemoteFolder string `json:"remotefolder"`
-	RemoteUser   string `json:"remoteuser"`
-	RemotePass   string `json:"remotepass"`
-	FilePrefix   string `json:"fileprefix"`
-	Region       string `json:"region"`
+	FtpName      string  `json:"ftpname"`
+	RemoteServer string  `json:"servername"`
+	RemoteFolder Folders `json:"remotefolder"`
+	RemoteUser   string  `json:"remoteuser"`
+	RemotePass   string  `json:"remotepass"`
+	FilePrefix   string  `json:"fileprefix"`
+	FilePattern  string  `json:"filepattern"`
+	Region       string  `json:"region"`
 	DateFind     string
-	Part         string `json:"part"`
+	Part         string     `json:"part"`
+	Files        []FileRule `json:"files"`
+	// NeName is the NE name in the dump header, or the SYSOBJECTID, for
+	// dumps that are not named after the NE.
+	NeName string `json:"nename"`
+	// Include and Exclude are globs on the names of the members inside the
+	// downloaded archives, matched against the full and the base name.
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
 }
 
 func (c *Config) FillDate(cd string) {
@@ -29,8 +39,46 @@ type Table struct {
 	Fpath     string
 	Header    []string
 	HeaderMap map[string]int64
-	File      io.WriteCloser
+	// Types holds the inferred type of each column of Header, see InferType.
+	Types []string
+	File  io.WriteCloser
+	// Rows writes the rows to File as they are parsed.
+	Rows      *csv.Writer
 	Buffer    *bytes.Buffer
 	ListFile  []string
 	TableName []string
+	// ListColumns are the columns of each file of ListFile.
+	ListColumns [][]string
+}
+
+// Schema returns the columns of the table with their types, columns without
+// any value are enums.
+func (t *Table) Schema() Schema {
+	s := Schema{Table: t.Name}
+	for i, h := range t.Header {
+		typ := TypeEnum
+		if i < len(t.Types) && t.Types[i] != "" {
+			typ = t.Types[i]
+		}
+		s.Columns = append(s.Columns, Column{Name: h, Type: typ})
+	}
+	for i, f := range t.ListFile {
+		sf := SchemaFile{File: f, Table: t.TableName[i]}
+		if i < len(t.ListColumns) {
+			sf.Columns = t.ListColumns[i]
+		}
+		s.Files = append(s.Files, sf)
+	}
+	return s
+}
+
+// InferRow merges the types of the values of row, from column from on, into
+// the column types.
+func (t *Table) InferRow(row []string, from int) {
+	for len(t.Types) < len(row) {
+		t.Types = append(t.Types, "")
+	}
+	for i := from; i < len(row); i++ {
+		t.Types[i] = MergeType(t.Types[i], InferType(row[i]))
+	}
 }
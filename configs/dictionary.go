@@ -0,0 +1,47 @@
+package configs
+
+import (
+	"strings"
+)
+
+// ParamInfo describes one parameter of a MO in the parameter dictionary.
+// An empty MO applies to the parameter in every MO.
+//
+// JSON dictionaries use the keys below. CSV dictionaries and the
+// _PARAM_DICT table name the same fields MO, PARAMETER, FULL NAME, UNIT,
+// VALUE RANGE and ENUM MEANINGS.
+type ParamInfo struct {
+	MO       string `json:"mo"`
+	Param    string `json:"parameter"`
+	FullName string `json:"fullname"`
+	Unit     string `json:"unit"`
+	Range    string `json:"range"`
+	// Enums are the meanings of the values, "0:OFF;1:ON".
+	Enums string `json:"enums"`
+}
+
+// ParamDict holds the dictionary entries by MO and parameter.
+type ParamDict map[string]ParamInfo
+
+// NewParamDict indexes entries, later entries win.
+func NewParamDict(entries []ParamInfo) ParamDict {
+	d := make(ParamDict)
+	for _, e := range entries {
+		e.MO = strings.ToUpper(strings.TrimSpace(e.MO))
+		e.Param = strings.ToUpper(strings.TrimSpace(e.Param))
+		if e.Param == "" {
+			continue
+		}
+		d[e.MO+"."+e.Param] = e
+	}
+	return d
+}
+
+// Lookup returns the entry of param in mo, or of param in any MO.
+func (d ParamDict) Lookup(mo, param string) (ParamInfo, bool) {
+	if e, ok := d[mo+"."+param]; ok {
+		return e, true
+	}
+	e, ok := d["."+param]
+	return e, ok
+}
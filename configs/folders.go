@@ -0,0 +1,36 @@
+package configs
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Folders is the remotefolder of a NE. It is written either as one folder or
+// as a list of candidates, and every entry may contain glob wildcards, e.g.
+// "/bam/version_*/ftp/export_cfgmml/".
+type Folders []string
+
+func (f *Folders) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*f = Folders{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*f = Folders(many)
+	return nil
+}
+
+func (f Folders) MarshalJSON() ([]byte, error) {
+	if len(f) == 1 {
+		return json.Marshal(f[0])
+	}
+	return json.Marshal([]string(f))
+}
+
+func (f Folders) String() string {
+	return strings.Join(f, ", ")
+}
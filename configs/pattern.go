@@ -0,0 +1,167 @@
+package configs
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// regexPrefix marks a filepattern as a regular expression instead of a glob.
+const regexPrefix = "re:"
+
+const (
+	SelectNewest = "newest"
+	SelectAll    = "all"
+)
+
+// FileRule describes one file to fetch from the export folder of a NE.
+//
+// FilePattern is either a glob ("CFGMML-RNC1127-*.zip") or, prefixed with
+// "re:", a regular expression. Named groups date (yyyymmdd), time and ip are
+// used to check the export date and the server and to rank candidates.
+type FileRule struct {
+	FilePrefix  string `json:"fileprefix"`
+	FilePattern string `json:"filepattern"`
+	Select      string `json:"select"`
+	LocalName   string `json:"localname"`
+	Optional    bool   `json:"optional"`
+
+	fileRegexp *regexp.Regexp
+}
+
+// FileMatch is the result of matching one remote file name against a FileRule.
+type FileMatch struct {
+	Name string
+	Date string
+	Time string
+	IP   string
+}
+
+// Validate checks the remote folders, the member patterns and every file
+// rule and compiles the regular expressions.
+// Without a files list the NE gets a single rule from fileprefix/filepattern.
+func (c *Config) Validate() error {
+	if len(c.RemoteFolder) == 0 {
+		return fmt.Errorf("%s: remotefolder is required", c.FtpName)
+	}
+	for _, f := range c.RemoteFolder {
+		if _, err := path.Match(f, ""); err != nil {
+			return fmt.Errorf("%s: invalid remotefolder %q: %s", c.FtpName, f, err.Error())
+		}
+	}
+
+	for _, m := range append(append([]string(nil), c.Include...), c.Exclude...) {
+		if _, err := path.Match(m, ""); err != nil {
+			return fmt.Errorf("%s: invalid member pattern %q: %s", c.FtpName, m, err.Error())
+		}
+	}
+
+	if len(c.Files) == 0 {
+		c.Files = []FileRule{{FilePrefix: c.FilePrefix, FilePattern: c.FilePattern}}
+	}
+
+	localNames := make(map[string]bool)
+	for i := range c.Files {
+		r := &c.Files[i]
+		if err := r.validate(); err != nil {
+			return fmt.Errorf("%s: %s", c.FtpName, err.Error())
+		}
+		if localNames[r.LocalName] {
+			return fmt.Errorf("%s: duplicate localname %q in files", c.FtpName, r.LocalName)
+		}
+		localNames[r.LocalName] = true
+	}
+	return nil
+}
+
+func (r *FileRule) validate() error {
+	switch r.Select {
+	case "":
+		r.Select = SelectNewest
+	case SelectNewest, SelectAll:
+	default:
+		return fmt.Errorf("unknown select %q, use %s or %s", r.Select, SelectNewest, SelectAll)
+	}
+
+	if strings.ContainsAny(r.LocalName, `/\. `) {
+		return fmt.Errorf("invalid localname %q", r.LocalName)
+	}
+
+	if r.FilePattern == "" {
+		if r.FilePrefix == "" {
+			return fmt.Errorf("fileprefix or filepattern is required")
+		}
+		return nil
+	}
+
+	if strings.HasPrefix(r.FilePattern, regexPrefix) {
+		re, err := regexp.Compile("^(?:" + strings.TrimPrefix(r.FilePattern, regexPrefix) + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid filepattern %q: %s", r.FilePattern, err.Error())
+		}
+		for _, n := range re.SubexpNames() {
+			switch n {
+			case "", "date", "time", "ip":
+			default:
+				return fmt.Errorf("unknown group %q in filepattern, use date, time or ip", n)
+			}
+		}
+		r.fileRegexp = re
+		return nil
+	}
+
+	if _, err := path.Match(r.FilePattern, ""); err != nil {
+		return fmt.Errorf("invalid filepattern %q: %s", r.FilePattern, err.Error())
+	}
+	return nil
+}
+
+// MatchFile reports whether name is an export for dateFind served by host.
+//
+// Without a filepattern the name has to contain both fileprefix and dateFind.
+// A glob has to match the whole name and the name has to contain dateFind.
+// A regular expression has to match the whole name; its date group, when
+// present, replaces the dateFind check and its ip group has to equal host.
+func (r *FileRule) MatchFile(name, dateFind, host string) (FileMatch, bool) {
+	m := FileMatch{Name: name}
+
+	switch {
+	case r.FilePattern == "":
+		return m, strings.Contains(name, r.FilePrefix) && strings.Contains(name, dateFind)
+
+	case r.fileRegexp != nil:
+		sub := r.fileRegexp.FindStringSubmatch(name)
+		if sub == nil {
+			return m, false
+		}
+		hasDate := false
+		for i, n := range r.fileRegexp.SubexpNames() {
+			switch n {
+			case "date":
+				m.Date = sub[i]
+				hasDate = true
+			case "time":
+				m.Time = sub[i]
+			case "ip":
+				m.IP = sub[i]
+			}
+		}
+		if m.IP != "" && m.IP != host {
+			return m, false
+		}
+		if hasDate {
+			// dateFind may carry a suffix after the date, see FillDate
+			return m, m.Date != "" && strings.HasPrefix(dateFind, m.Date)
+		}
+		return m, strings.Contains(name, dateFind)
+
+	case strings.HasPrefix(r.FilePattern, regexPrefix):
+		// validate was never called
+		return m, false
+
+	default:
+		ok, _ := path.Match(r.FilePattern, name)
+		return m, ok && strings.Contains(name, dateFind)
+	}
+}
@@ -0,0 +1,114 @@
+package configs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFileRuleValidate(t *testing.T) {
+	for _, tc := range []struct {
+		rule FileRule
+		err  string
+	}{
+		{FileRule{FilePrefix: "CFGMML-RNC1-"}, ""},
+		{FileRule{FilePattern: "CFGMML-RNC1-*.zip"}, ""},
+		{FileRule{FilePattern: `re:CFGMML-RNC1-(?P<ip>[0-9.]+)-(?P<date>\d{8})(?P<time>\d{6})\.zip`}, ""},
+		{FileRule{FilePrefix: "CFGMML-RNC1-", Select: SelectAll, LocalName: "part"}, ""},
+		{FileRule{}, "fileprefix or filepattern is required"},
+		{FileRule{FilePattern: "CFGMML-[RNC1-*.zip"}, "invalid filepattern"},
+		{FileRule{FilePattern: "re:CFGMML-(RNC1-.*"}, "invalid filepattern"},
+		{FileRule{FilePattern: `re:CFGMML-RNC1-(?P<day>\d{8})\.zip`}, `unknown group "day"`},
+		{FileRule{FilePrefix: "CFGMML-RNC1-", Select: "oldest"}, `unknown select "oldest"`},
+		{FileRule{FilePrefix: "CFGMML-RNC1-", LocalName: "part.1"}, `invalid localname "part.1"`},
+	} {
+		err := tc.rule.validate()
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("%+v: %v", tc.rule, err)
+		case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+			t.Errorf("%+v: got %v, want %s", tc.rule, err, tc.err)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	for _, tc := range []struct {
+		cfg Config
+		err string
+	}{
+		{Config{FtpName: "A", RemoteFolder: Folders{"/bam/version_*/"}, FilePrefix: "CFGMML-RNC1-"}, ""},
+		{Config{FtpName: "A", FilePrefix: "CFGMML-RNC1-"}, "remotefolder is required"},
+		{Config{FtpName: "A", RemoteFolder: Folders{"/bam/[/"}, FilePrefix: "CFGMML-RNC1-"}, "invalid remotefolder"},
+		{Config{FtpName: "A", RemoteFolder: Folders{"/bam/"}, FilePrefix: "CFGMML-RNC1-", Include: []string{"[*.txt"}}, "invalid member pattern"},
+		{Config{FtpName: "A", RemoteFolder: Folders{"/bam/"}, FilePattern: "re:(?P<host>.*)"}, `A: unknown group "host"`},
+		{Config{FtpName: "A", RemoteFolder: Folders{"/bam/"}, Files: []FileRule{{FilePrefix: "CFGMML-"}, {FilePrefix: "NEINFO-"}}}, `duplicate localname ""`},
+	} {
+		err := tc.cfg.Validate()
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("%+v: %v", tc.cfg, err)
+		case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+			t.Errorf("%+v: got %v, want %s", tc.cfg, err, tc.err)
+		}
+	}
+
+	// a top-level prefix becomes the only rule
+	cfg := Config{FtpName: "A", RemoteFolder: Folders{"/bam/"}, FilePrefix: "CFGMML-RNC1-"}
+	if err := cfg.Validate(); err != nil || len(cfg.Files) != 1 || cfg.Files[0].FilePrefix != "CFGMML-RNC1-" || cfg.Files[0].Select != SelectNewest {
+		t.Errorf("files %+v: %v", cfg.Files, err)
+	}
+}
+
+func TestFileRuleMatchFile(t *testing.T) {
+	const (
+		dateFind = "202112020"
+		host     = "10.0.0.1"
+		regex    = `re:CFGMML-RNC1-(?P<ip>[0-9.]+)-(?P<date>\d{8})(?P<time>\d{6})\.zip`
+	)
+	for _, tc := range []struct {
+		rule FileRule
+		name string
+		ok   bool
+		want FileMatch
+	}{
+		// a prefix alone still tells RNC1 from RNC11
+		{FileRule{FilePrefix: "CFGMML-RNC1-"}, "CFGMML-RNC1-10.0.0.1-20211202083000.zip", true, FileMatch{}},
+		{FileRule{FilePrefix: "CFGMML-RNC1-"}, "CFGMML-RNC11-10.0.0.1-20211202083000.zip", false, FileMatch{}},
+		{FileRule{FilePrefix: "CFGMML-RNC1-"}, "CFGMML-RNC1-10.0.0.1-20211201083000.zip", false, FileMatch{}},
+
+		// a glob matches the whole name and restricts the extension
+		{FileRule{FilePattern: "CFGMML-RNC1-*.zip"}, "CFGMML-RNC1-10.0.0.1-20211202083000.zip", true, FileMatch{}},
+		{FileRule{FilePattern: "CFGMML-RNC1-*.zip"}, "CFGMML-RNC11-10.0.0.1-20211202083000.zip", false, FileMatch{}},
+		{FileRule{FilePattern: "CFGMML-RNC1-*.zip"}, "CFGMML-RNC1-10.0.0.1-20211202083000.txt", false, FileMatch{}},
+		{FileRule{FilePattern: "CFGMML-RNC1-*.zip"}, "CFGMML-RNC1-10.0.0.1-20211201083000.zip", false, FileMatch{}},
+
+		// a regular expression captures date, time and ip
+		{FileRule{FilePattern: regex}, "CFGMML-RNC1-10.0.0.1-20211202083000.zip", true, FileMatch{Date: "20211202", Time: "083000", IP: host}},
+		{FileRule{FilePattern: regex}, "CFGMML-RNC11-10.0.0.1-20211202083000.zip", false, FileMatch{}},
+		{FileRule{FilePattern: regex}, "CFGMML-RNC1-10.0.0.2-20211202083000.zip", false, FileMatch{}},
+		{FileRule{FilePattern: regex}, "CFGMML-RNC1-10.0.0.1-20211201083000.zip", false, FileMatch{}},
+		{FileRule{FilePattern: regex}, "x-CFGMML-RNC1-10.0.0.1-20211202083000.zip", false, FileMatch{}},
+
+		// without a date group the name has to contain the date
+		{FileRule{FilePattern: `re:CFGMML-RNC1-.*\.zip`}, "CFGMML-RNC1-10.0.0.1-20211202083000.zip", true, FileMatch{}},
+		{FileRule{FilePattern: `re:CFGMML-RNC1-.*\.zip`}, "CFGMML-RNC1-10.0.0.1-20211201083000.zip", false, FileMatch{}},
+	} {
+		rule := tc.rule
+		if err := rule.validate(); err != nil {
+			t.Fatal(err)
+		}
+		m, ok := rule.MatchFile(tc.name, dateFind, host)
+		if ok != tc.ok {
+			t.Errorf("%s on %s: match %v, want %v", rule.FilePattern+rule.FilePrefix, tc.name, ok, tc.ok)
+			continue
+		}
+		if tc.ok && (m.Name != tc.name || m.Date != tc.want.Date || m.Time != tc.want.Time || m.IP != tc.want.IP) {
+			t.Errorf("%s on %s: %+v, want %+v", rule.FilePattern+rule.FilePrefix, tc.name, m, tc.want)
+		}
+	}
+
+	// a regular expression that was never compiled matches nothing
+	if _, ok := (&FileRule{FilePattern: regex}).MatchFile("CFGMML-RNC1-10.0.0.1-20211202083000.zip", dateFind, host); ok {
+		t.Error("unvalidated regular expression matched")
+	}
+}
@@ -0,0 +1,93 @@
+package configs
+
+import (
+	"fmt"
+	"path"
+)
+
+const (
+	ParamSwitch = "switch"
+	ParamList   = "list"
+	ParamNever  = "never"
+)
+
+// Output formats of hex values, see ParamRules.HexFormat.
+const (
+	HexDecimal = "decimal"
+	HexAsIs    = "hex"
+	HexBoth    = "both"
+)
+
+// ParamRules tell how compound values such as
+// HSPAPLUSSWITCH=64QAM-1&MIMO-0 are written to the tables.
+//
+// Every entry is a glob on the parameter name ("HSPAPLUSSWITCH",
+// "*CELLNAME*") or on <MO>.<parameter> ("UCELLSETUP.HSPAPLUSSWITCH").
+// Switch parameters are bit fields expanded into one <PARAM>_<BIT> column
+// per bit next to the packed value. List parameters and parameters that
+// are never expanded keep the packed value only.
+//
+// Hex values (H'2B67) are converted to decimal, except for parameters in
+// Hex, kept as written, and in HexBoth, written as decimal with the value
+// as written in an extra <PARAM>_HEX column.
+type ParamRules struct {
+	Switch  []string `json:"switch"`
+	List    []string `json:"list"`
+	Never   []string `json:"never"`
+	Hex     []string `json:"hex"`
+	HexBoth []string `json:"hexboth"`
+}
+
+// DefaultParamRules are used without a rules file.
+var DefaultParamRules = ParamRules{
+	Never: []string{"REMARK", "*CELLNAME*"},
+}
+
+// Validate checks every pattern.
+func (r ParamRules) Validate() error {
+	for _, patterns := range [][]string{r.Switch, r.List, r.Never, r.Hex, r.HexBoth} {
+		for _, p := range patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid parameter pattern %q: %s", p, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// Kind returns ParamNever, ParamSwitch or ParamList for the parameter param
+// of mo, in this order of precedence, or empty when no rule lists it.
+func (r ParamRules) Kind(mo, param string) string {
+	switch {
+	case matchParam(r.Never, mo, param):
+		return ParamNever
+	case matchParam(r.Switch, mo, param):
+		return ParamSwitch
+	case matchParam(r.List, mo, param):
+		return ParamList
+	}
+	return ""
+}
+
+// HexFormat returns HexAsIs, HexBoth or HexDecimal for the parameter param of mo.
+func (r ParamRules) HexFormat(mo, param string) string {
+	switch {
+	case matchParam(r.Hex, mo, param):
+		return HexAsIs
+	case matchParam(r.HexBoth, mo, param):
+		return HexBoth
+	}
+	return HexDecimal
+}
+
+func matchParam(patterns []string, mo, param string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, param); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, mo+"."+param); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package configs
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Column types inferred from the values written to a table, see InferType.
+const (
+	TypeInteger = "integer"
+	TypeDecimal = "decimal"
+	TypeEnum    = "enum"
+	TypeText    = "text"
+	TypeIP      = "ip"
+	TypeHex     = "hex"
+)
+
+// maxTextWidth is the longest value a Text column of Access holds, longer
+// ones need a Memo.
+const maxTextWidth = 255
+
+// maxExactDecimal is the largest whole number a double holds exactly, bigger
+// ones only survive as text.
+const maxExactDecimal = 1 << 53
+
+var (
+	decimalValue = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+	hexValue     = regexp.MustCompile(`^H'[0-9A-Fa-f]+$`)
+)
+
+// Column is one column of a table and its type.
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// SchemaFile is one CSV file of a table, tables wider than 255 columns are
+// split over several.
+type SchemaFile struct {
+	File    string   `json:"file"`
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+}
+
+// Schema is written next to the CSV files of a table so the sinks create
+// typed columns instead of guessing them from the text.
+type Schema struct {
+	Table   string       `json:"table"`
+	Columns []Column     `json:"columns"`
+	Files   []SchemaFile `json:"files"`
+}
+
+// InferType returns the type of a value as written to a table, empty for an
+// empty value.
+//
+// Integers fit in 32 bits, the long of Access, larger whole numbers are
+// decimals up to 2^53. Quoted values are text, except IP addresses, and
+// unquoted words such as ON or SF7 are enums. Numbers with leading zeros
+// are enums too so the zeros are kept. Values longer than 255 characters,
+// such as long packed switches, are text whatever they look like.
+func InferType(v string) string {
+	if v == "" {
+		return ""
+	}
+	if utf8.RuneCountInString(v) > maxTextWidth {
+		return TypeText
+	}
+	unquoted := v
+	if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+		unquoted = v[1 : len(v)-1]
+	}
+	if strings.ContainsAny(unquoted, ".:") && net.ParseIP(unquoted) != nil {
+		return TypeIP
+	}
+	if unquoted != v {
+		return TypeText
+	}
+	if hexValue.MatchString(v) {
+		return TypeHex
+	}
+	if !decimalValue.MatchString(v) {
+		return TypeEnum
+	}
+	digits := strings.TrimPrefix(v, "-")
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+		return TypeEnum
+	}
+	if _, err := strconv.ParseInt(v, 10, 32); err == nil {
+		return TypeInteger
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil && f <= maxExactDecimal && f >= -maxExactDecimal {
+		return TypeDecimal
+	}
+	return TypeText
+}
+
+// MergeType returns the type of a column holding values of both types.
+// Integers widen to decimals, any other mix to enum, and text wins over all.
+func MergeType(a, b string) string {
+	switch {
+	case a == "" || a == b:
+		return b
+	case b == "":
+		return a
+	case a == TypeText || b == TypeText:
+		return TypeText
+	case isNumeric(a) && isNumeric(b):
+		return TypeDecimal
+	}
+	return TypeEnum
+}
+
+func isNumeric(t string) bool {
+	return t == TypeInteger || t == TypeDecimal
+}
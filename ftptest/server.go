@@ -0,0 +1,455 @@
+// Package ftptest provides an in-process FTP server that serves files from
+// memory, for end-to-end tests of the download pipeline without a real BAM.
+//
+// It implements the subset of the protocol the downloader speaks: login,
+// CWD/PWD, passive mode, LIST, RETR and SIZE. Listings use the DOS style of
+// the BAM servers unless ListFormat is set to FormatUnix. Delays and failure
+// replies can be configured per command.
+//
+// There is no SFTP counterpart: the downloader only speaks FTP, so an SFTP
+// server would have nothing to test. Add one together with an SFTP download
+// path.
+package ftptest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	FormatDOS  = "dos"
+	FormatUnix = "unix"
+)
+
+// File is one file served by the Server.
+type File struct {
+	Data    []byte
+	ModTime time.Time
+}
+
+// Server is a minimal FTP server listening on a loopback port.
+type Server struct {
+	// User and Pass are the accepted credentials, any login is accepted when User is empty.
+	User string
+	Pass string
+	// ListFormat is FormatDOS (default) or FormatUnix.
+	ListFormat string
+
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu     sync.Mutex
+	files  map[string]File
+	delays map[string]time.Duration
+	fails  map[string]string
+	hooks  map[string]func()
+	log    []string
+	conns  map[net.Conn]bool
+	closed bool
+}
+
+// NewServer starts a server on 127.0.0.1 with a random port.
+func NewServer() (*Server, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		listener: l,
+		files:    make(map[string]File),
+		delays:   make(map[string]time.Duration),
+		fails:    make(map[string]string),
+		hooks:    make(map[string]func()),
+		conns:    make(map[net.Conn]bool),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the host:port the server listens on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the server, drops open sessions and waits for them to end.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+// AddFile serves data at the absolute path name, replacing any previous content.
+func (s *Server) AddFile(name string, data []byte, modTime time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[path.Clean("/"+name)] = File{Data: data, ModTime: modTime}
+}
+
+// RemoveFile stops serving name.
+func (s *Server) RemoveFile(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.files, path.Clean("/"+name))
+}
+
+// Delay makes the server wait d before answering cmd. cmd is either a bare
+// command ("RETR") or a command with its argument ("RETR /bam/x.zip").
+func (s *Server) Delay(cmd string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delays[commandKey(cmd)] = d
+}
+
+// Fail makes the server answer cmd with reply instead of executing it, cmd
+// is matched like in Delay. An empty reply drops the connection instead.
+func (s *Server) Fail(cmd, reply string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fails[commandKey(cmd)] = reply
+}
+
+// OnCommand calls fn before the server answers cmd, cmd is matched like in
+// Delay. Tests use it to change files between two listings.
+func (s *Server) OnCommand(cmd string, fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks[commandKey(cmd)] = fn
+}
+
+// commandKey normalizes "CMD arg" so that "list /a/b/" and "LIST /a/b" match.
+func commandKey(cmd string) string {
+	if i := strings.Index(cmd, " "); i >= 0 {
+		arg := cmd[i+1:]
+		if strings.HasPrefix(arg, "/") {
+			arg = path.Clean(arg)
+		}
+		return strings.ToUpper(cmd[:i]) + " " + arg
+	}
+	return strings.ToUpper(cmd)
+}
+
+// Commands returns every command received so far, arguments included.
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.log...)
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = true
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			newSession(s, conn).run()
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+func (s *Server) isDir(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if name == "/" {
+		return true
+	}
+	for f := range s.files {
+		if strings.HasPrefix(f, name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) file(name string) (File, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.files[name]
+	return f, ok
+}
+
+// list returns the listing lines of the directory dir.
+func (s *Server) list(dir string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	dirs := make(map[string]time.Time)
+	var names []string
+	for name, f := range s.files {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(name, prefix)
+		if i := strings.Index(rest, "/"); i >= 0 {
+			if f.ModTime.After(dirs[rest[:i]]) {
+				dirs[rest[:i]] = f.ModTime
+			}
+			continue
+		}
+		names = append(names, rest)
+	}
+	for d := range dirs {
+		names = append(names, d+"/")
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, n := range names {
+		if strings.HasSuffix(n, "/") {
+			n = strings.TrimSuffix(n, "/")
+			lines = append(lines, s.formatEntry(n, 0, dirs[n], true))
+			continue
+		}
+		f := s.files[prefix+n]
+		lines = append(lines, s.formatEntry(n, int64(len(f.Data)), f.ModTime, false))
+	}
+	return lines
+}
+
+func (s *Server) formatEntry(name string, size int64, mod time.Time, dir bool) string {
+	if s.ListFormat == FormatUnix {
+		perm := "-rw-r--r--"
+		if dir {
+			perm = "drwxr-xr-x"
+		}
+		return fmt.Sprintf("%s   1 ftp      ftp      %8d %s %s", perm, size, mod.Format("Jan 02 15:04"), name)
+	}
+	sizeCol := fmt.Sprintf("%20d", size)
+	if dir {
+		sizeCol = fmt.Sprintf("%-20s", "       <DIR>")
+	}
+	return fmt.Sprintf("%s  %s %s", mod.Format("01-02-06  03:04PM"), sizeCol, name)
+}
+
+type session struct {
+	srv    *Server
+	conn   net.Conn
+	r      *bufio.Reader
+	cwd    string
+	user   string
+	logged bool
+	pasv   net.Listener
+}
+
+func newSession(s *Server, conn net.Conn) *session {
+	return &session{srv: s, conn: conn, r: bufio.NewReader(conn), cwd: "/"}
+}
+
+func (c *session) reply(format string, args ...interface{}) {
+	fmt.Fprintf(c.conn, format+"\r\n", args...)
+}
+
+func (c *session) run() {
+	defer c.conn.Close()
+	defer c.closePasv()
+
+	c.reply("220 ftptest ready")
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd, arg := line, ""
+		if i := strings.Index(line, " "); i >= 0 {
+			cmd, arg = line[:i], line[i+1:]
+		}
+		cmd = strings.ToUpper(cmd)
+
+		key := commandKey(cmd + " " + arg)
+		c.srv.mu.Lock()
+		c.srv.log = append(c.srv.log, line)
+		delay, ok := c.srv.delays[key]
+		if !ok {
+			delay = c.srv.delays[cmd]
+		}
+		fail, failing := c.srv.fails[key]
+		if !failing {
+			fail, failing = c.srv.fails[cmd]
+		}
+		hook, ok := c.srv.hooks[key]
+		if !ok {
+			hook = c.srv.hooks[cmd]
+		}
+		closed := c.srv.closed
+		c.srv.mu.Unlock()
+
+		if closed {
+			return
+		}
+		if hook != nil {
+			hook()
+		}
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+		if failing {
+			if fail == "" {
+				return
+			}
+			c.reply("%s", fail)
+			continue
+		}
+
+		if !c.handle(cmd, arg) {
+			return
+		}
+	}
+}
+
+func (c *session) abs(p string) string {
+	if p == "" {
+		return c.cwd
+	}
+	if !strings.HasPrefix(p, "/") {
+		p = path.Join(c.cwd, p)
+	}
+	return path.Clean(p)
+}
+
+// handle executes one command and reports whether the session goes on.
+func (c *session) handle(cmd, arg string) bool {
+	switch cmd {
+	case "USER":
+		c.user = arg
+		c.reply("331 Password required")
+		return true
+	case "PASS":
+		if c.srv.User != "" && (c.user != c.srv.User || arg != c.srv.Pass) {
+			c.reply("530 Login incorrect")
+			return true
+		}
+		c.logged = true
+		c.reply("230 Logged in")
+		return true
+	case "QUIT":
+		c.reply("221 Bye")
+		return false
+	case "NOOP":
+		c.reply("200 OK")
+		return true
+	}
+
+	if !c.logged {
+		c.reply("530 Not logged in")
+		return true
+	}
+
+	switch cmd {
+	case "SYST":
+		c.reply("215 Windows_NT")
+	case "TYPE":
+		c.reply("200 Type set to %s", arg)
+	case "PWD":
+		c.reply("257 \"%s\" is current directory", c.cwd)
+	case "CWD":
+		dir := c.abs(arg)
+		if !c.srv.isDir(dir) {
+			c.reply("550 %s: No such directory", arg)
+			return true
+		}
+		c.cwd = dir
+		c.reply("250 CWD command successful")
+	case "PASV":
+		c.closePasv()
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			c.reply("425 Cannot open data connection")
+			return true
+		}
+		c.pasv = l
+		port := l.Addr().(*net.TCPAddr).Port
+		c.reply("227 Entering Passive Mode (127,0,0,1,%d,%d)", port>>8, port&0xff)
+	case "SIZE":
+		f, ok := c.srv.file(c.abs(arg))
+		if !ok {
+			c.reply("550 %s: No such file", arg)
+			return true
+		}
+		c.reply("213 %d", len(f.Data))
+	case "LIST", "NLST":
+		dir := c.abs(arg)
+		if !c.srv.isDir(dir) {
+			c.reply("550 %s: No such directory", arg)
+			return true
+		}
+		lines := c.srv.list(dir)
+		if cmd == "NLST" {
+			for i, l := range lines {
+				lines[i] = l[strings.LastIndex(l, " ")+1:]
+			}
+		}
+		c.transfer(strings.NewReader(strings.Join(lines, "\r\n") + "\r\n"))
+	case "RETR":
+		f, ok := c.srv.file(c.abs(arg))
+		if !ok {
+			c.reply("550 %s: No such file", arg)
+			return true
+		}
+		c.transfer(strings.NewReader(string(f.Data)))
+	default:
+		// MLSD, AUTH, EPSV and friends are not supported, like on the BAM
+		c.reply("502 Command not implemented")
+	}
+	return true
+}
+
+// transfer sends r over the passive data connection.
+func (c *session) transfer(r io.Reader) {
+	if c.pasv == nil {
+		c.reply("425 Use PASV first")
+		return
+	}
+	c.reply("150 Opening data connection")
+	c.pasv.(*net.TCPListener).SetDeadline(time.Now().Add(10 * time.Second))
+	dc, err := c.pasv.Accept()
+	c.closePasv()
+	if err != nil {
+		c.reply("425 Cannot open data connection")
+		return
+	}
+	_, err = io.Copy(dc, r)
+	dc.Close()
+	if err != nil {
+		c.reply("426 Transfer aborted")
+		return
+	}
+	c.reply("226 Transfer complete")
+}
+
+func (c *session) closePasv() {
+	if c.pasv != nil {
+		c.pasv.Close()
+		c.pasv = nil
+	}
+}
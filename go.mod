@@ -4,7 +4,6 @@ go 1.17
 
 require (
 	github.com/alexbrainman/odbc v0.0.0-20210605012845-39f8520b0d5f
-	github.com/gen2brain/go-unarr v0.1.1
 	github.com/jmoiron/sqlx v1.3.4
 	github.com/sirupsen/logrus v1.8.1
 	gopkg.in/dutchcoders/goftp.v1 v1.0.0-20170301105846-ed59a591ce14
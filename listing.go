@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// remoteFile is one entry of a remote directory listing.
+type remoteFile struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// parseListLine understands the DOS style listing of the BAM FTP servers as
+// well as Unix "ls -l" and MLSD lines.
+//
+//	12-02-21  08:30AM             37192705 CFGMML-RNC1127-10.7.245.18-20211202083000.zip
+//	-rw-r--r--   1 ftp      ftp      37192705 Dec 02 08:30 CFGMML-RNC1127-10.7.245.18-20211202083000.zip
+//	type=file;size=37192705;modify=20211202083000; CFGMML-RNC1127-10.7.245.18-20211202083000.zip
+func parseListLine(line string) (remoteFile, bool) {
+	line = strings.TrimRight(line, "\r\n")
+	if strings.TrimSpace(line) == "" {
+		return remoteFile{}, false
+	}
+
+	if i := strings.Index(line, "; "); i > 0 && strings.Contains(line[:i], "=") {
+		return parseMlsdLine(line[:i], line[i+2:])
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) >= 4 && len(fields[0]) == 8 && fields[0][2] == '-' {
+		f := remoteFile{Name: nthFieldRest(line, 3)}
+		f.ModTime, _ = time.Parse("01-02-06 03:04PM", fields[0]+" "+fields[1])
+		if fields[2] == "<DIR>" {
+			f.IsDir = true
+		} else {
+			f.Size, _ = strconv.ParseInt(fields[2], 10, 64)
+		}
+		return f, f.Name != ""
+	}
+
+	if len(fields) >= 9 && len(fields[0]) == 10 {
+		f := remoteFile{Name: nthFieldRest(line, 8), IsDir: fields[0][0] == 'd'}
+		f.Size, _ = strconv.ParseInt(fields[4], 10, 64)
+		stamp := fields[5] + " " + fields[6] + " " + fields[7]
+		if strings.Contains(fields[7], ":") {
+			t, err := time.Parse("Jan 2 15:04", stamp)
+			if err == nil {
+				// no year means within the last six months
+				now := time.Now()
+				t = t.AddDate(now.Year(), 0, 0)
+				if t.After(now.AddDate(0, 0, 1)) {
+					t = t.AddDate(-1, 0, 0)
+				}
+			}
+			f.ModTime = t
+		} else {
+			f.ModTime, _ = time.Parse("Jan 2 2006", stamp)
+		}
+		return f, f.Name != ""
+	}
+
+	return remoteFile{}, false
+}
+
+func parseMlsdLine(facts, name string) (remoteFile, bool) {
+	f := remoteFile{Name: name}
+	for _, fact := range strings.Split(facts, ";") {
+		kv := strings.SplitN(fact, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch strings.ToLower(kv[0]) {
+		case "type":
+			f.IsDir = strings.Contains(strings.ToLower(kv[1]), "dir")
+		case "size":
+			f.Size, _ = strconv.ParseInt(kv[1], 10, 64)
+		case "modify":
+			if len(kv[1]) >= 14 {
+				f.ModTime, _ = time.Parse("20060102150405", kv[1][:14])
+			}
+		}
+	}
+	return f, f.Name != ""
+}
+
+// nthFieldRest returns line from its n-th whitespace separated field onwards,
+// keeping spaces inside file names.
+func nthFieldRest(line string, n int) string {
+	rest := strings.TrimLeft(line, " \t")
+	for i := 0; i < n; i++ {
+		idx := strings.IndexAny(rest, " \t")
+		if idx < 0 {
+			return ""
+		}
+		rest = strings.TrimLeft(rest[idx:], " \t")
+	}
+	return rest
+}
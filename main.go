@@ -7,6 +7,7 @@ import (
 	"crypto/tls"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -14,9 +15,13 @@ import (
 	"io/ioutil"
 	logStd "log"
 	"math"
+	"math/big"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -26,20 +31,62 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/aksafarand/ftpdownloader/configs"
+	"github.com/aksafarand/ftpdownloader/mml"
 
 	_ "github.com/alexbrainman/odbc"
-	"github.com/gen2brain/go-unarr"
 	"gopkg.in/dutchcoders/goftp.v1"
 )
 
-var downloadFailed int
-var allTables map[string]*configs.Table
+// runOptions are the command line switches that shape one run.
+type runOptions struct {
+	SkipDoubleSlash bool
+	CommentMode     string
+	RawOnly         bool
+	KeepCSV         bool
+	CSVOnly         bool
+	VerbMode        string
+	ParamRules      string
+	ParamDict       string
+	ParamAlias      bool
+	FallbackDays    int
+	StableInterval  time.Duration
+	StableMaxWait   time.Duration
+	ExtractLimits   extractLimits
+	Workers         int
+}
+
+// commentMode returns how commented commands are handled, CommentMode or
+// else skip or strip after SkipDoubleSlash.
+func (o runOptions) commentMode() string {
+	if o.CommentMode != "" {
+		return o.CommentMode
+	}
+	if o.SkipDoubleSlash {
+		return commentSkip
+	}
+	return commentStrip
+}
+
+// validCommentMode accepts the values of -comment-mode, empty leaves it to
+// -skip-comment.
+func validCommentMode(mode string) bool {
+	switch mode {
+	case "", commentSkip, commentStrip, commentFlag:
+		return true
+	}
+	return false
+}
+
+// stagingDir holds the dumps parsed in a result folder when -keep-csv asks
+// for them, one sub folder per NE and downloaded file:
+// <folder>/_staging/<ftpname>/<dump>/.
+const stagingDir = "_staging"
 
 func copyNationalResultToFolder(src, dest, techName string) ([]string, error) {
 	var resultCopy []string
 	_, err := os.Stat(dest)
 	if os.IsNotExist(err) {
-		if err := os.MkdirAll(dest, 0666); err != nil {
+		if err := os.MkdirAll(dest, 0755); err != nil {
 			return nil, err
 		}
 	}
@@ -75,145 +122,461 @@ func copyNationalResultToFolder(src, dest, techName string) ([]string, error) {
 	return resultCopy, nil
 }
 
-func ftpDownload(remoteServer, remoteFolder, remoteUser, remotePass, currentDate, serverName, filePrefix string, wg *sync.WaitGroup, region, national, dateNaming string) {
+func ftpDownload(cfg configs.Config, techName, currentDate string, opts runOptions, wg *sync.WaitGroup, region, national string, manifest *downloadManifest) {
 	defer wg.Done()
-	var err error
-	var ftp *goftp.FTP
 
-	if ftp, err = goftp.Connect(remoteServer); err != nil {
-		log.Errorf("Cannot Connect To: %s ServerName: %s Err: %s", remoteServer, serverName, err.Error())
-		downloadFailed++
-		return
+	entry := manifestEntry{FtpName: cfg.FtpName, Region: cfg.Region, Part: cfg.Part, Status: statusFailed}
+	defer func() { manifest.add(entry) }()
+
+	ftp, listings, err := ftpList(cfg)
+	if ftp != nil {
+		defer ftp.Close()
+	}
+
+	if err == nil {
+		sources, err := fetchRemoteFiles(ftp, listings, cfg, opts, cfg.DateFind, currentDate, region, national)
+		if err != nil {
+			log.Errorf("%s In: %s From: %s", err.Error(), cfg.RemoteFolder, cfg.FtpName)
+		} else {
+			entry.Status = statusDownloaded
+			entry.DumpDate = currentDate
+			entry.Source = strings.Join(sources, " | ")
+			entry.Folder = remoteFolders(sources)
+			return
+		}
+	}
 
+	if opts.FallbackDays < 1 {
+		return
 	}
 
-	defer ftp.Close()
+	day, err := time.Parse("20060102", currentDate)
+	if err != nil {
+		log.Errorf("Cannot Use Fallback For: %s Invalid Date: %s", cfg.FtpName, currentDate)
+		return
+	}
 
-	if err = ftp.Login(remoteUser, remotePass); err != nil {
-		config := tls.Config{
-			InsecureSkipVerify: true,
-			ClientAuth:         tls.RequestClientCert,
+	// walk back one day at a time so the most recent good dump wins,
+	// trying the remote listing first and then our own earlier results
+	for d := 1; d <= opts.FallbackDays; d++ {
+		prevDate := day.AddDate(0, 0, -d).Format("20060102")
+
+		if listings != nil {
+			sources, err := fetchRemoteFiles(ftp, listings, cfg, opts, strings.Replace(cfg.DateFind, currentDate, prevDate, 1), currentDate, region, national)
+			if err == nil {
+				entry.Status = statusFallback
+				entry.DumpDate = prevDate
+				entry.Source = strings.Join(sources, " | ")
+				entry.Folder = remoteFolders(sources)
+				log.Warnf("Fallback: %s Using Remote Dump Of: %s", cfg.FtpName, prevDate)
+				return
+			}
 		}
 
-		if err = ftp.AuthTLS(&config); err != nil {
-			log.Errorf("Cannot Login To: %s ServerName: %s Err: %s", remoteServer, serverName, err.Error())
-			downloadFailed++
-			return
+		sources, err := copyLocalDumps(cfg, techName, prevDate, currentDate, region, national)
+		if err != nil {
+			log.Errorf("Cannot Copy Fallback For: %s Err: %s", cfg.FtpName, err.Error())
+			continue
 		}
-		if err = ftp.Login(remoteUser, remotePass); err != nil {
-			log.Errorf("Cannot Login To: %s ServerName: %s Err: %s", remoteServer, serverName, err.Error())
-			downloadFailed++
+		if len(sources) > 0 {
+			entry.Status = statusFallback
+			entry.DumpDate = prevDate
+			entry.Source = strings.Join(sources, " | ")
+			log.Warnf("Fallback: %s Using Local Dump Of: %s", cfg.FtpName, prevDate)
 			return
 		}
 	}
 
-	if err = ftp.Cwd(remoteFolder); err != nil {
-		log.Errorf("Cannot Open Folder From: %s From: %s ServerName: %s Err: %s", remoteFolder, remoteServer, serverName, err.Error())
-		downloadFailed++
-		return
+	log.Errorf("No Fallback Dump Found For: %s Within %d Day(s)", cfg.FtpName, opts.FallbackDays)
+}
+
+// fetchRemoteFiles downloads the files of every rule of cfg for dateFind and
+// returns their remote paths. A required rule without a match fails the
+// whole NE, and whatever was already saved for it is removed again.
+func fetchRemoteFiles(ftp *goftp.FTP, listings []remoteListing, cfg configs.Config, opts runOptions, dateFind, dateNaming, region, national string) ([]string, error) {
+	var sources, saved []string
+	cleanup := func() {
+		for _, fName := range saved {
+			os.Remove(filepath.Join(region, fName))
+			os.Remove(filepath.Join(national, fName))
+		}
 	}
 
-	var curpath string
-	if curpath, err = ftp.Pwd(); err != nil {
-		log.Errorf("Cannot Open Folder From: %s From: %s ServerName: %s Err: %s", remoteFolder, remoteServer, serverName, err.Error())
+	for _, rule := range cfg.Files {
+		picked := findRemoteFiles(listings, cfg, rule, dateFind)
+		if len(picked) == 0 {
+			if rule.Optional {
+				log.Warnf("Cannot Find Optional Files %s From: %s", ruleName(rule), cfg.FtpName)
+				continue
+			}
+			cleanup()
+			return nil, fmt.Errorf("Cannot Find Files %s", ruleName(rule))
+		}
 
-		return
+		for i, c := range picked {
+			p := path.Join(c.folder, c.Name)
+			if opts.StableInterval > 0 {
+				if err := waitStable(ftp, cfg, c, opts.StableInterval, opts.StableMaxWait); err != nil {
+					cleanup()
+					return nil, err
+				}
+			}
+
+			fName := cfg.FtpName + "_" + dateNaming
+			if rule.LocalName != "" {
+				fName += "_" + rule.LocalName
+			}
+			if len(picked) > 1 {
+				fName += fmt.Sprintf("_%d", i+1)
+			}
+			fName += path.Ext(p)
+
+			if err := ftpRetr(ftp, p, fName, region, national); err != nil {
+				cleanup()
+				return nil, fmt.Errorf("Cannot Download: %s Err: %s", p, err.Error())
+			}
+			saved = append(saved, fName)
+			sources = append(sources, p)
+			log.Printf("Download: %s From: %s To: %s", fName, cfg.FtpName, region)
+		}
 	}
 
-	var files []string
-	if files, err = ftp.List(remoteFolder); err != nil {
-		log.Errorf("Cannot List Files: %s From: %s ServerName: %s Err: %s", remoteFolder, remoteServer, serverName, err.Error())
+	return sources, nil
+}
 
-		return
+// copyLocalDumps copies the raw files kept in result/<prevDate> for cfg into
+// today's folders under today's names.
+func copyLocalDumps(cfg configs.Config, techName, prevDate, currentDate, region, national string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join("result", prevDate, techName, cfg.Region, cfg.FtpName+"_"+prevDate+"*"))
+	if err != nil {
+		return nil, err
 	}
 
-	var path string
-	var fName string
-	var ext string
-	var tFile int
-	for _, f := range files {
+	var sources []string
+	for _, m := range matches {
+		// plain text dumps are raw files too, only our own outputs are left
+		if ext := filepath.Ext(m); ext == ".accdb" || ext == ".csv" {
+			continue
+		}
+		fName := cfg.FtpName + "_" + currentDate + strings.TrimPrefix(filepath.Base(m), cfg.FtpName+"_"+prevDate)
+		src, err := os.Open(m)
+		if err != nil {
+			return nil, err
+		}
+		err = saveDump(src, fName, region, national)
+		src.Close()
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, m)
+	}
+	return sources, nil
+}
+
+// remoteFolders lists the distinct folders of the downloaded paths.
+func remoteFolders(sources []string) string {
+	var folders []string
+	seen := make(map[string]bool)
+	for _, s := range sources {
+		if d := path.Dir(s); !seen[d] {
+			seen[d] = true
+			folders = append(folders, d)
+		}
+	}
+	return strings.Join(folders, " | ")
+}
 
-		s := strings.Split(f, " ")
-		for _, fs := range s {
+func ruleName(rule configs.FileRule) string {
+	if rule.FilePattern != "" {
+		return "Matching: " + rule.FilePattern
+	}
+	return "With Prefix: " + rule.FilePrefix
+}
 
-			if strings.Contains(fs, filePrefix) && strings.Contains(fs, currentDate) {
+// remoteListing is the listing of one export folder of a NE.
+type remoteListing struct {
+	Folder string
+	Lines  []string
+}
 
-				if strings.Contains(f, ":") {
-					dSplit := strings.Split(f, ":")
-					tSplit := strings.Split(dSplit[0], "  ")
-					tData, _ := strconv.Atoi(tSplit[1])
-					if tFile == 0 {
-						tFile = tData
-						path = filepath.Join(curpath, strings.TrimSpace(fs))
-						ext = filepath.Ext(strings.TrimSpace(fs))
-						fName = serverName + "_" + dateNaming + ext
+// ftpList connects to the NE and lists every candidate export folder,
+// expanding wildcards. Folders that cannot be opened are logged and skipped;
+// it only fails when none could be listed. The connection is returned even
+// on error so the caller can close it.
+func ftpList(cfg configs.Config) (*goftp.FTP, []remoteListing, error) {
+	var err error
+	var ftp *goftp.FTP
 
-					}
-					if tFile < tData {
-						tFile = tData
-						path = filepath.Join(curpath, strings.TrimSpace(fs))
-						ext = filepath.Ext(strings.TrimSpace(fs))
-						fName = serverName + "_" + dateNaming + ext
+	if ftp, err = goftp.Connect(cfg.RemoteServer); err != nil {
+		log.Errorf("Cannot Connect To: %s ServerName: %s Err: %s", cfg.RemoteServer, cfg.FtpName, err.Error())
+		return nil, nil, err
+	}
 
-					}
+	if err = ftp.Login(cfg.RemoteUser, cfg.RemotePass); err != nil {
+		config := tls.Config{
+			InsecureSkipVerify: true,
+			ClientAuth:         tls.RequestClientCert,
+		}
+
+		if err = ftp.AuthTLS(&config); err != nil {
+			log.Errorf("Cannot Login To: %s ServerName: %s Err: %s", cfg.RemoteServer, cfg.FtpName, err.Error())
+			return ftp, nil, err
+		}
+		if err = ftp.Login(cfg.RemoteUser, cfg.RemotePass); err != nil {
+			log.Errorf("Cannot Login To: %s ServerName: %s Err: %s", cfg.RemoteServer, cfg.FtpName, err.Error())
+			return ftp, nil, err
+		}
+	}
+
+	var folders []string
+	for _, f := range cfg.RemoteFolder {
+		expanded, err := expandRemoteFolder(ftp, f)
+		if err != nil {
+			log.Errorf("Cannot Expand Folder: %s From: %s ServerName: %s Err: %s", f, cfg.RemoteServer, cfg.FtpName, err.Error())
+			continue
+		}
+		folders = append(folders, expanded...)
+	}
+
+	var listings []remoteListing
+	for _, folder := range folders {
+		if err = ftp.Cwd(folder); err != nil {
+			log.Errorf("Cannot Open Folder From: %s From: %s ServerName: %s Err: %s", folder, cfg.RemoteServer, cfg.FtpName, err.Error())
+			continue
+		}
+
+		var curpath string
+		if curpath, err = ftp.Pwd(); err != nil {
+			log.Errorf("Cannot Open Folder From: %s From: %s ServerName: %s Err: %s", folder, cfg.RemoteServer, cfg.FtpName, err.Error())
+			continue
+		}
+
+		var files []string
+		if files, err = ftp.List(folder); err != nil {
+			log.Errorf("Cannot List Files: %s From: %s ServerName: %s Err: %s", folder, cfg.RemoteServer, cfg.FtpName, err.Error())
+			continue
+		}
+		listings = append(listings, remoteListing{Folder: curpath, Lines: files})
+	}
+
+	if len(listings) == 0 {
+		if err == nil {
+			err = fmt.Errorf("no folder matches %s", cfg.RemoteFolder)
+			log.Errorf("Cannot Find Folder: %s From: %s ServerName: %s", cfg.RemoteFolder, cfg.RemoteServer, cfg.FtpName)
+		}
+		return ftp, nil, err
+	}
+	return ftp, listings, nil
+}
+
+// expandRemoteFolder resolves the wildcards of an absolute folder pattern by
+// listing the remote directories one level at a time.
+func expandRemoteFolder(ftp *goftp.FTP, pattern string) ([]string, error) {
+	if !strings.ContainsAny(pattern, "*?[") {
+		return []string{pattern}, nil
+	}
+	if !strings.HasPrefix(pattern, "/") {
+		return nil, fmt.Errorf("wildcards need an absolute folder")
+	}
+
+	dirs := []string{"/"}
+	for _, seg := range strings.Split(strings.Trim(pattern, "/"), "/") {
+		if seg == "" {
+			continue
+		}
+		var next []string
+		for _, dir := range dirs {
+			if !strings.ContainsAny(seg, "*?[") {
+				next = append(next, path.Join(dir, seg))
+				continue
+			}
+			lines, err := ftp.List(dir)
+			if err != nil {
+				return nil, err
+			}
+			for _, l := range lines {
+				rf, ok := parseListLine(l)
+				if !ok || !rf.IsDir {
+					continue
 				}
+				if ok, _ := path.Match(seg, rf.Name); ok {
+					next = append(next, path.Join(dir, rf.Name))
+				}
+			}
+		}
+		dirs = next
+	}
+	return dirs, nil
+}
 
+// findRemoteFiles returns the remote paths of the files in the listings that
+// match rule for dateFind. With the newest policy only the latest one over
+// all folders is kept and the reason is logged when there was a choice; with
+// the all policy every match of the folder holding the latest one is
+// returned in name order.
+func findRemoteFiles(listings []remoteListing, cfg configs.Config, rule configs.FileRule, dateFind string) []remoteCandidate {
+	host := strings.Split(cfg.RemoteServer, ":")[0]
+
+	var candidates []remoteCandidate
+	for _, l := range listings {
+		for _, line := range l.Lines {
+			rf, ok := parseListLine(line)
+			if !ok || rf.IsDir {
+				continue
+			}
+			if m, ok := rule.MatchFile(rf.Name, dateFind, host); ok {
+				candidates = append(candidates, remoteCandidate{remoteFile: rf, folder: l.Folder, match: m})
 			}
 		}
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
 
+	names := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		names = append(names, path.Join(c.folder, c.Name))
 	}
+	best, reason := pickRemoteFile(candidates)
 
-	if fName == "" {
-		log.Errorf("Cannot Find Files In: %s From: %s", remoteFolder, serverName)
-		downloadFailed++
-		return
+	if rule.Select == configs.SelectAll {
+		var picked []remoteCandidate
+		for _, c := range candidates {
+			if c.folder == best.folder {
+				picked = append(picked, c)
+			}
+		}
+		sort.Slice(picked, func(i, j int) bool { return picked[i].Name < picked[j].Name })
+		return picked
 	}
-	_, err = ftp.Retr(path, func(r io.Reader) error {
 
-		var regionBuf, nationalBuf bytes.Buffer
+	if len(candidates) > 1 {
+		log.Infof("Selected: %s From: %s Out Of %d Candidates By %s: %s", path.Join(best.folder, best.Name), cfg.FtpName, len(candidates), reason, strings.Join(names, ", "))
+	}
 
-		writer := io.MultiWriter(&regionBuf, &nationalBuf)
+	return []remoteCandidate{best}
+}
 
-		if _, err = io.Copy(writer, r); err != nil {
-			downloadFailed++
-			return err
+// waitStable re-lists the folder of c every interval until its size and
+// modification time stay the same between two listings, giving up after
+// maxWait. The BAM writes the export in place, so a file that still grows
+// would be downloaded truncated.
+func waitStable(ftp *goftp.FTP, cfg configs.Config, c remoteCandidate, interval, maxWait time.Duration) error {
+	p := path.Join(c.folder, c.Name)
+	prev := c.remoteFile
+	start := time.Now()
+	log.Infof("Waiting For Stable File: %s From: %s Size: %d", p, cfg.FtpName, prev.Size)
+
+	for {
+		if time.Since(start)+interval > maxWait {
+			log.Errorf("File Still Changing: %s From: %s After: %s", p, cfg.FtpName, time.Since(start))
+			return fmt.Errorf("File Still Changing After %s: %s", maxWait, p)
 		}
+		time.Sleep(interval)
 
-		destinationRegion, err := os.Create(fmt.Sprintf("%s/%s", region, fName))
+		lines, err := ftp.List(c.folder)
 		if err != nil {
-			downloadFailed++
-			return err
+			return fmt.Errorf("Cannot List Files: %s Err: %s", c.folder, err.Error())
 		}
-		if _, err = io.Copy(destinationRegion, &regionBuf); err != nil {
-			downloadFailed++
-			return err
+		var cur remoteFile
+		found := false
+		for _, l := range lines {
+			if rf, ok := parseListLine(l); ok && rf.Name == c.Name {
+				cur, found = rf, true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("File Disappeared: %s", p)
 		}
 
-		destinationNational, err := os.Create(fmt.Sprintf("%s/%s", national, fName))
-		if err != nil {
-			downloadFailed++
-			return err
+		if cur.Size == prev.Size && cur.ModTime.Equal(prev.ModTime) {
+			log.Infof("File Stable: %s From: %s Size: %d After: %s", p, cfg.FtpName, cur.Size, time.Since(start))
+			return nil
 		}
-		if _, err = io.Copy(destinationNational, &nationalBuf); err != nil {
-			downloadFailed++
-			return err
+		log.Infof("File Changing: %s From: %s Size: %d -> %d", p, cfg.FtpName, prev.Size, cur.Size)
+		prev = cur
+	}
+}
+
+type remoteCandidate struct {
+	remoteFile
+	folder string
+	match  configs.FileMatch
+}
+
+// pickRemoteFile orders candidates by the date/time captured from the file
+// name, then by modification time, then by name, and returns the first one
+// together with the criterion that decided it.
+func pickRemoteFile(c []remoteCandidate) (remoteCandidate, string) {
+	stamp := func(x remoteCandidate) string { return x.match.Date + x.match.Time }
+
+	sort.SliceStable(c, func(i, j int) bool {
+		if stamp(c[i]) != stamp(c[j]) {
+			return stamp(c[i]) > stamp(c[j])
 		}
+		if !c[i].ModTime.Equal(c[j].ModTime) {
+			return c[i].ModTime.After(c[j].ModTime)
+		}
+		return c[i].Name > c[j].Name
+	})
 
-		return nil
+	switch {
+	case len(c) == 1:
+		return c[0], "Single Match"
+	case stamp(c[0]) != stamp(c[1]):
+		return c[0], "Newest Date/Time In File Name"
+	case !c[0].ModTime.Equal(c[1].ModTime):
+		return c[0], "Newest Modification Time"
+	default:
+		return c[0], "Last File Name"
+	}
+}
+
+func ftpRetr(ftp *goftp.FTP, path, fName, region, national string) error {
+	_, err := ftp.Retr(path, func(r io.Reader) error {
+		return saveDump(r, fName, region, national)
 	})
+	return err
+}
+
+// saveDump writes one raw dump into both the region and the national folder.
+func saveDump(r io.Reader, fName, region, national string) error {
+	var regionBuf, nationalBuf bytes.Buffer
+
+	writer := io.MultiWriter(&regionBuf, &nationalBuf)
+
+	if _, err := io.Copy(writer, r); err != nil {
+		return err
+	}
+
+	destinationRegion, err := os.Create(fmt.Sprintf("%s/%s", region, fName))
 	if err != nil {
-		downloadFailed++
-		return
+		return err
+	}
+	defer destinationRegion.Close()
+	if _, err = io.Copy(destinationRegion, &regionBuf); err != nil {
+		return err
+	}
+
+	destinationNational, err := os.Create(fmt.Sprintf("%s/%s", national, fName))
+	if err != nil {
+		return err
+	}
+	defer destinationNational.Close()
+	if _, err = io.Copy(destinationNational, &nationalBuf); err != nil {
+		return err
 	}
-	log.Printf("Download: %s From: %s To: %s", fName, serverName, region)
 
+	return nil
 }
 
 func AppInfo() string {
 	return "Huawei Dump 2G/3G Maker - Kukuh Wikartomo - 2021 v2021.12 | kukuh.wikartomo@huawei.com"
 }
 
-func dataProcess(techName string, currentDate string, info chan string, skipDoubleSlash, rawOnly, keepCsv bool) string {
+func dataProcess(techName string, currentDate string, info chan string, opts runOptions) string {
 
 	var jsonFile string
 	var fileName string
@@ -241,42 +604,58 @@ func dataProcess(techName string, currentDate string, info chan string, skipDoub
 
 	for i := range ftpConfigs {
 		ftpConfigs[i].FillDate(currentDate)
+		if err := ftpConfigs[i].Validate(); err != nil {
+			log.Fatalf("Invalid Config In %s: %s", jsonFile, err.Error())
+		}
 	}
 
 	mapConfig := make(map[string]string)
 
+	// every file rule with a prefix names loose dumps, Validate already
+	// turned a top-level fileprefix into a rule
 	for _, f := range ftpConfigs {
 		ipAddr := strings.Split(f.RemoteServer, ":")
-		fPrefix := f.FilePrefix
-		fullPrefix := fmt.Sprintf("%s%s", fPrefix, ipAddr[0])
-		mapConfig[fullPrefix] = f.FtpName
+		for _, r := range f.Files {
+			if r.FilePrefix == "" {
+				continue
+			}
+			fullPrefix := fmt.Sprintf("%s%s", r.FilePrefix, ipAddr[0])
+			mapConfig[fullPrefix] = f.FtpName
+		}
 	}
 
-	if err := os.MkdirAll("result", 0666); err != nil {
+	if err := os.MkdirAll("result", 0755); err != nil {
 		panic(err)
 	}
 
-	if err := os.MkdirAll(filepath.Join("result", currentDate), 0666); err != nil {
+	if err := os.MkdirAll(filepath.Join("result", currentDate), 0755); err != nil {
 		panic(err)
 	}
 
-	if err := os.MkdirAll(filepath.Join("result", currentDate, techName), 0666); err != nil {
+	if err := os.MkdirAll(filepath.Join("result", currentDate, techName), 0755); err != nil {
 		panic(err)
 	}
 
-	if err := os.MkdirAll(filepath.Join("result", currentDate, techName, "National"), 0666); err != nil {
+	if err := os.MkdirAll(filepath.Join("result", currentDate, techName, "National"), 0755); err != nil {
 		panic(err)
 	}
 
 	resultNational := filepath.Join("result", currentDate, techName, "National")
 	resultRegion := filepath.Join("result", currentDate, techName)
 
-	go processDownload(techName, ftpConfigs, info, resultRegion, resultNational, currentDate)
+	manifest := newDownloadManifest()
+	go processDownload(techName, ftpConfigs, info, resultRegion, resultNational, currentDate, opts, manifest)
 
 	logInfo := <-info
 	log.Info(logInfo)
 
-	if rawOnly {
+	manifestFile := filepath.Join(resultRegion, "manifest.csv")
+	if err := manifest.write(manifestFile); err != nil {
+		log.Errorf("Cannot Write Manifest: %s", err.Error())
+	}
+	dumpDates := manifest.dumpDates()
+
+	if opts.RawOnly {
 		return ""
 	}
 
@@ -292,29 +671,12 @@ func dataProcess(techName string, currentDate string, info chan string, skipDoub
 
 	}
 
-	logStd.Println("Extracting Data For National")
-	err = unArr(filepath.Join("result", currentDate, techName, "National"), "0", "", true, currentDate)
-	if err != nil {
-		log.Errorf("Cannot Extract File From: %s", "National")
-	}
-
-	regionMap := make(map[string]int)
+	neNames := make(map[string]bool)
 	for _, c := range ftpConfigs {
-		if _, ok := regionMap[c.Region]; !ok {
-			regionMap[c.Region] = 0
-		}
-	}
-
-	// extract region only
-	for r := range regionMap {
-		logStd.Printf("Extracting Data For %s\n", r)
-		err = unArr(filepath.Join("result", currentDate, techName, r), "0", "", false, currentDate)
-		if err != nil {
-			log.Errorf("Cannot Extract File From: %s", r)
-		}
+		neNames[c.FtpName] = true
 	}
 
-	t := listAccessLocation(filepath.Join("result", currentDate, techName))
+	t := listAccessLocation(filepath.Join("result", currentDate, techName), currentDate, neNames)
 
 	var accessTemplate []byte
 
@@ -365,7 +727,7 @@ func dataProcess(techName string, currentDate string, info chan string, skipDoub
 	}
 	nationalSplit := false
 	for _, f := range files {
-		if f.IsDir() {
+		if f.IsDir() && strings.HasPrefix(f.Name(), "National_") {
 			nationalSplit = true
 		}
 	}
@@ -387,32 +749,97 @@ func dataProcess(techName string, currentDate string, info chan string, skipDoub
 		}
 	}
 
-	t = listAccessLocation(filepath.Join("result", currentDate, techName))
+	t = listAccessLocation(filepath.Join("result", currentDate, techName), currentDate, neNames)
 
-	var wg sync.WaitGroup
-	// var part int
-	// if len(nationalMapPart) > 0 {
-	// 	part = len(nationalMapPart) - 1
-	// }
-	// fmt.Println("part:", part, t)
-	wg.Add(len(t))
+	rules := loadParamRules(opts.ParamRules)
+	dict := loadParamDict(opts.ParamDict)
+	report := newParseReport()
 
-	for k, v := range t {
-		if err := os.MkdirAll(filepath.Join("result", currentDate, techName, k, "_dumpresult"), 0666); err != nil {
-			panic(err)
+	// every dump is parsed once, the folders holding it are assembled from
+	// its tables; the copies of a downloaded dump share its name
+	folders := make([]string, 0, len(t))
+	for k := range t {
+		folders = append(folders, k)
+	}
+	sort.Strings(folders)
+	var dumps []dumpFile
+	members := make(map[string][]int)
+	parsed := make(map[string]int)
+	for _, k := range folders {
+		list, err := listDumps(t[k], currentDate, neNames)
+		if err != nil {
+			log.Fatal(err)
 		}
-		if strings.Contains(k, "National") {
-
-			go MainProcess(v, filepath.Join(parentDir, "result", currentDate, techName, k, "_dumpresult"), skipDoubleSlash, fileName, true, keepCsv, filepath.Join(parentDir, "result", currentDate, techName, k, (techName+"_DUMP_HW_"+k+"_"+currentDate+".accdb")), false, &wg, nationalMapPart, currentDate, ftpConfigs, mapConfig)
-		} else {
+		for _, d := range list {
+			key := d.Path
+			if d.NeName != "" {
+				key = filepath.Base(d.Path)
+			}
+			i, ok := parsed[key]
+			if !ok {
+				i = len(dumps)
+				parsed[key] = i
+				dumps = append(dumps, d)
+			}
+			members[k] = append(members[k], i)
+		}
+	}
 
-			go MainProcess(v, filepath.Join(parentDir, "result", currentDate, techName, k, "_dumpresult"), skipDoubleSlash, fileName, true, keepCsv, filepath.Join(parentDir, "result", currentDate, techName, k, (techName+"_HW_"+k+"_"+currentDate+".accdb")), false, &wg, nationalMapPart, currentDate, ftpConfigs, mapConfig)
+	neAliases := make(map[string]string)
+	for _, c := range ftpConfigs {
+		neAliases[c.FtpName] = c.FtpName
+		if c.NeName != "" {
+			neAliases[c.NeName] = c.FtpName
 		}
+	}
+	ctx := &parseContext{
+		ResultDir:   filepath.Join(parentDir, resultRegion, fragmentDir),
+		CommentMode: opts.commentMode(),
+		VerbMode:    opts.VerbMode,
+		CurrentDate: currentDate,
+		DumpDates:   dumpDates,
+		NeAliases:   neAliases,
+		Rules:       rules,
+		Dict:        dict,
+		Report:      report,
+	}
+	fragments := parseDumps(dumps, ctx, opts.Workers, opts.KeepCSV, opts.ExtractLimits, ftpConfigs, mapConfig, manifest)
 
+	var wg sync.WaitGroup
+	wg.Add(len(t))
+	folderTables := make(map[string]map[string]*configs.Table)
+	for _, k := range folders {
+		if err := os.MkdirAll(filepath.Join("result", currentDate, techName, k, "_dumpresult"), 0755); err != nil {
+			panic(err)
+		}
+		var frags []*parseContext
+		for _, i := range members[k] {
+			frags = append(frags, fragments[i])
+		}
+		folderTables[k] = make(map[string]*configs.Table)
+		go MainProcess(filepath.Join(parentDir, "result", currentDate, techName, k, "_dumpresult"), frags, ctx, opts.ParamAlias, folderTables[k], &wg)
 	}
 
 	wg.Wait()
+	if err := os.RemoveAll(ctx.ResultDir); err != nil {
+		log.Errorf("Error Delete Temp Dir: %s", ctx.ResultDir)
+	}
 	logStd.Println("Parsing Raw Data Done")
+
+	if err := manifest.write(manifestFile); err != nil {
+		log.Errorf("Cannot Write Manifest: %s", err.Error())
+	}
+	if err := report.write(filepath.Join(resultRegion, "parse_report.csv")); err != nil {
+		log.Errorf("Cannot Write Parse Report: %s", err.Error())
+	}
+	if n := report.count(); n > 0 {
+		log.Warnf("%d Parse Issues (%s), See: %s", n, report.summary(), filepath.Join(resultRegion, "parse_report.csv"))
+	}
+
+	if opts.CSVOnly {
+		return filepath.Join(parentDir, resultNational)
+	}
+
 	// Access Export
 
 	var wg2 sync.WaitGroup
@@ -421,10 +848,10 @@ func dataProcess(techName string, currentDate string, info chan string, skipDoub
 
 		if strings.Contains(k, "National") {
 
-			GoAccess(v, filepath.Join(parentDir, "result", currentDate, techName, k, "_dumpresult"), skipDoubleSlash, fileName, true, keepCsv, filepath.Join(parentDir, "result", currentDate, techName, k, (techName+"_DUMP_HW_"+k+"_"+currentDate+".accdb")), false, nationalMapPart, currentDate, ftpConfigs, mapConfig, &wg2)
+			GoAccess(v, filepath.Join(parentDir, "result", currentDate, techName, k, "_dumpresult"), opts.SkipDoubleSlash, fileName, true, opts.KeepCSV, filepath.Join(parentDir, "result", currentDate, techName, k, (techName+"_DUMP_HW_"+k+"_"+currentDate+".accdb")), false, nationalMapPart, currentDate, ftpConfigs, mapConfig, folderTables[k], &wg2)
 		} else {
 
-			GoAccess(v, filepath.Join(parentDir, "result", currentDate, techName, k, "_dumpresult"), skipDoubleSlash, fileName, true, keepCsv, filepath.Join(parentDir, "result", currentDate, techName, k, (techName+"_HW_"+k+"_"+currentDate+".accdb")), false, nationalMapPart, currentDate, ftpConfigs, mapConfig, &wg2)
+			GoAccess(v, filepath.Join(parentDir, "result", currentDate, techName, k, "_dumpresult"), opts.SkipDoubleSlash, fileName, true, opts.KeepCSV, filepath.Join(parentDir, "result", currentDate, techName, k, (techName+"_HW_"+k+"_"+currentDate+".accdb")), false, nationalMapPart, currentDate, ftpConfigs, mapConfig, folderTables[k], &wg2)
 		}
 
 	}
@@ -436,28 +863,21 @@ func dataProcess(techName string, currentDate string, info chan string, skipDoub
 	// 		panic(err)
 	// 	}
 	// 	if strings.Contains(k, "National") {
-	// 		MainProcess(v, filepath.Join(parentDir, "result", currentDate, techName, k, "_dumpresult"), skipDoubleSlash, fileName, true, keepCsv, filepath.Join(parentDir, "result", currentDate, techName, k, (techName+"_DUMP_HW_"+k+"_"+currentDate+".accdb")), false, &wg, nationalMapPart, currentDate, ftpConfigs, mapConfig)
+	// 		MainProcess(v, filepath.Join(parentDir, "result", currentDate, techName, k, "_dumpresult"), opts.SkipDoubleSlash, fileName, true, opts.KeepCSV, filepath.Join(parentDir, "result", currentDate, techName, k, (techName+"_DUMP_HW_"+k+"_"+currentDate+".accdb")), false, &wg, nationalMapPart, currentDate, ftpConfigs, mapConfig)
 	// 	}
 
 	// }
 
 	for k, v := range t {
-		for _, s := range find(v, ".txt") {
-			if nationalSplit && strings.Contains(filepath.Base(v), "National") {
-				if !keepCsv {
-					err := os.RemoveAll(v)
-					if err != nil {
-						log.Errorf("Failed To Remove Temp File: %s", s)
-					}
-				}
-			} else {
-				err := os.Remove(s)
+		if nationalSplit && strings.Contains(filepath.Base(v), "National") {
+			if !opts.KeepCSV {
+				err := os.RemoveAll(v)
 				if err != nil {
-					log.Errorf("Failed To Remove Temp File: %s", s)
+					log.Errorf("Failed To Remove Temp File: %s", v)
 				}
 			}
 		}
-		if !keepCsv {
+		if !opts.KeepCSV {
 			if err := os.RemoveAll(filepath.Join(parentDir, "result", currentDate, techName, k, "_dumpresult")); err != nil {
 				log.Errorf("Error Delete Temp Dir: %s", filepath.Join(parentDir, "result", currentDate, techName, k, "_dumpresult"))
 
@@ -465,7 +885,7 @@ func dataProcess(techName string, currentDate string, info chan string, skipDoub
 		}
 	}
 
-	if !keepCsv {
+	if !opts.KeepCSV {
 		logStd.Println("Removing Temp Files Done")
 	}
 
@@ -501,24 +921,71 @@ func dataProcess(techName string, currentDate string, info chan string, skipDoub
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "parse" {
+		if err := runParse(os.Args[2:], os.Stdout); err != nil && err != flag.ErrHelp {
+			logStd.Fatalf("Cannot Parse: %s", err.Error())
+		}
+		return
+	}
+
 	flagTech := flag.String("tech", "", "Technology 2g/3g")
 	flagSkippedComment := flag.Bool("skip-comment", true, "Skipped // Lines")
 	flagGetDate := flag.String("date", "", "Get Specific Date in yyyymmdd")
 	flagRawOnly := flag.Bool("raw", false, "Get Raw Only")
 	flagKeepCSV := flag.Bool("keep-csv", false, "Keep Generated CSV for checking")
 	flagCopyToFolder := flag.String("copy-to", "", "Copy National Dump Result to Folder")
+	flagFallbackDays := flag.Int("fallback-days", 0, "Use Last Good Dump From Previous N Days When Today's Export Is Missing")
+	flagCSVOnly := flag.Bool("csv-only", false, "Stop After Parsing, Keep CSV and Skip Access Export")
+	flagParamRules := flag.String("param-rules", "./paramrules.json", "Rules For Switch and List Parameters, Built-in Defaults When Missing")
+	flagParamDict := flag.String("param-dict", "", "Parameter Dictionary (CSV or JSON) For the _PARAM_DICT Table and Missing Parameter Checks")
+	flagParamAlias := flag.Bool("param-alias", false, "Name Parameter Columns After Their Full Name In the Parameter Dictionary")
+	flagCommentMode := flag.String("comment-mode", "", "Commented // Commands: skip, strip (Parse As Active) or flag (Parse With IS_COMMENTED Column), Overrides -skip-comment")
+	flagWorkers := flag.Int("workers", runtime.NumCPU(), "Files Parsed In Parallel, One Pool Shared By All Dumps Of The Run")
+	flagVerbMode := flag.String("verb-mode", verbModeMerge, "Tables Per MO: add (ADD Only), merge (ADD and MOD) or separate (Every Verb, One Table Per Verb)")
+	flagStableInterval := flag.Duration("stable-interval", 0, "Re-list Remote File After This Interval Until Size and Time Stop Changing, 0 Disables")
+	flagStableMaxWait := flag.Duration("stable-max-wait", 10*time.Minute, "Maximum Wait For a Remote File to Become Stable")
+	flagMaxExtractMB := flag.Int64("max-extract-mb", defaultExtractLimits.MaxBytes>>20, "Maximum Extracted Size Per Downloaded File in MB, 0 Disables")
+	flagArchiveDepth := flag.Int("archive-depth", defaultExtractLimits.MaxDepth, "Open Archives Nested Inside Downloaded Files Up To This Many Levels, 0 Disables")
+	flagMaxExtractRatio := flag.Float64("max-extract-ratio", defaultExtractLimits.MaxRatio, "Maximum Extracted to Compressed Size Ratio Per Downloaded File, 0 Disables")
 	flag.Parse()
 	techName := strings.TrimSpace(strings.ToUpper(*flagTech))
-	skipDoubleSlash := *flagSkippedComment
-	rawOnly := *flagRawOnly
 	getDate := *flagGetDate
-	keepCSV := *flagKeepCSV
 	copyToFolder := *flagCopyToFolder
+	opts := runOptions{
+		SkipDoubleSlash: *flagSkippedComment,
+		CommentMode:     strings.ToLower(*flagCommentMode),
+		RawOnly:         *flagRawOnly,
+		KeepCSV:         *flagKeepCSV,
+		CSVOnly:         *flagCSVOnly,
+		VerbMode:        strings.ToLower(*flagVerbMode),
+		ParamRules:      *flagParamRules,
+		ParamDict:       *flagParamDict,
+		ParamAlias:      *flagParamAlias,
+		FallbackDays:    *flagFallbackDays,
+		StableInterval:  *flagStableInterval,
+		StableMaxWait:   *flagStableMaxWait,
+		ExtractLimits: extractLimits{
+			MaxBytes: *flagMaxExtractMB << 20,
+			MaxRatio: *flagMaxExtractRatio,
+			MaxDepth: *flagArchiveDepth,
+		},
+		Workers: *flagWorkers,
+	}
 
 	if techName == "" {
 		logStd.Fatalf("Technology not defined")
 	}
 
+	if !validCommentMode(opts.CommentMode) {
+		logStd.Fatalf("Unknown Comment Mode: %s, Use skip, strip or flag", opts.CommentMode)
+	}
+
+	switch opts.VerbMode {
+	case verbModeAdd, verbModeMerge, verbModeSeparate:
+	default:
+		logStd.Fatalf("Unknown Verb Mode: %s, Use add, merge or separate", opts.VerbMode)
+	}
+
 	var currentDate string
 	techName = strings.ToUpper(strings.TrimSpace(techName))
 
@@ -543,7 +1010,7 @@ func main() {
 		info2g := make(chan string)
 
 		logStd.Println("Starting 2G For", currentDate)
-		resultNationalFolder := dataProcess("2G", currentDate, info2g, skipDoubleSlash, rawOnly, keepCSV)
+		resultNationalFolder := dataProcess("2G", currentDate, info2g, opts)
 		if copyToFolder != "" {
 			res, err := copyNationalResultToFolder(resultNationalFolder, filepath.Join(copyToFolder, currentDate), "2G")
 			if err != nil {
@@ -568,7 +1035,7 @@ func main() {
 		info3g := make(chan string)
 
 		logStd.Println("Starting 3G For", currentDate)
-		resultNationalFolder := dataProcess("3G", currentDate, info3g, skipDoubleSlash, rawOnly, keepCSV)
+		resultNationalFolder := dataProcess("3G", currentDate, info3g, opts)
 		if copyToFolder != "" {
 			res, err := copyNationalResultToFolder(resultNationalFolder, filepath.Join(copyToFolder, currentDate), "3G")
 			if err != nil {
@@ -587,7 +1054,7 @@ func main() {
 
 }
 
-func processDownload(techName string, ftpConfigs []configs.Config, info chan string, resultRegion, resultNational, currentDate string) {
+func processDownload(techName string, ftpConfigs []configs.Config, info chan string, resultRegion, resultNational, currentDate string, opts runOptions, manifest *downloadManifest) {
 
 	wgDone := make(chan bool)
 	var wg sync.WaitGroup
@@ -595,14 +1062,14 @@ func processDownload(techName string, ftpConfigs []configs.Config, info chan str
 	startTime := time.Now()
 
 	for _, f := range ftpConfigs {
-		if err := os.MkdirAll(filepath.Join(resultRegion, f.Region), 0666); err != nil {
+		if err := os.MkdirAll(filepath.Join(resultRegion, f.Region), 0755); err != nil {
 			panic(err)
 		}
 	}
 
 	totalF := 0
 	for _, f := range ftpConfigs {
-		go ftpDownload(f.RemoteServer, f.RemoteFolder, f.RemoteUser, f.RemotePass, f.DateFind, f.FtpName, f.FilePrefix, &wg, filepath.Join(resultRegion, f.Region), resultNational, currentDate)
+		go ftpDownload(f, techName, currentDate, opts, &wg, filepath.Join(resultRegion, f.Region), resultNational, manifest)
 		totalF++
 
 	}
@@ -614,7 +1081,7 @@ func processDownload(techName string, ftpConfigs []configs.Config, info chan str
 
 	select {
 	case <-wgDone:
-		info <- fmt.Sprintf(techName+" - %v out of %v Files Downloaded In - %s", (totalF - downloadFailed), totalF, time.Since(startTime))
+		info <- fmt.Sprintf(techName+" - %v out of %v Files Downloaded, %v Substituted From Previous Dumps In - %s", manifest.count(statusDownloaded), totalF, manifest.count(statusFallback), time.Since(startTime))
 		break
 		// case err := <-fatalErrors:
 		// 	close(fatalErrors)
@@ -623,81 +1090,45 @@ func processDownload(techName string, ftpConfigs []configs.Config, info chan str
 
 }
 
-func unArr(location string, part string, ftpName string, isNational bool, currentDate string) error {
-
-	if isNational {
-		parentDir, _ := os.Getwd()
-		err := filepath.Walk(location,
-			func(files string, info os.FileInfo, err error) error {
-				if err != nil {
-					log.Error(err)
-				}
-
-				if path.Ext(info.Name()) == ".zip" {
-					if strings.Contains(filepath.Dir(files), "National") && strings.TrimSuffix(info.Name(), path.Ext(info.Name())) == ftpName+"_"+currentDate && part != "0" {
-						a, err := unarr.NewArchive(filepath.Join(parentDir, filepath.Dir(files), info.Name()))
-						if err != nil {
-							return fmt.Errorf("Cannot Extract: %s", filepath.Join(parentDir, filepath.Dir(files), info.Name()))
-						}
-						if err := os.MkdirAll(filepath.Join(parentDir, filepath.Dir(files), "National_"+part), 0666); err != nil {
-							panic(err)
-						}
-						a.Extract(filepath.Join(parentDir, filepath.Dir(files), "National_"+part))
-
-					}
-					if strings.Contains(filepath.Dir(files), "National") && part == "0" {
-						a, err := unarr.NewArchive(filepath.Join(parentDir, filepath.Dir(files), info.Name()))
-						if err != nil {
-							return fmt.Errorf("Cannot Extract: %s", filepath.Join(parentDir, filepath.Dir(files), info.Name()))
-						}
-
-						a.Extract(filepath.Join(parentDir, filepath.Dir(files)))
-
-					}
-
-				}
-				return nil
-			})
-		if err != nil {
-			return err
-		}
+// isRawDump reports whether info is a file downloaded for one of neNames,
+// whatever archive format or extension it has. Our own results are left alone.
+func isRawDump(info os.FileInfo, currentDate string, neNames map[string]bool) bool {
+	if info.IsDir() {
+		return false
 	}
+	switch filepath.Ext(info.Name()) {
+	case ".accdb", ".csv":
+		return false
+	}
+	neName, ok := neFromDumpName(info.Name(), currentDate)
+	return ok && neNames[neName]
+}
 
-	if !isNational {
-		parentDir, _ := os.Getwd()
-		err := filepath.Walk(location,
-			func(files string, info os.FileInfo, err error) error {
-				if err != nil {
-					log.Error(err)
-				}
-
-				if path.Ext(info.Name()) == ".zip" {
-
-					a, err := unarr.NewArchive(filepath.Join(parentDir, filepath.Dir(files), info.Name()))
-					if err != nil {
-						return fmt.Errorf("Cannot Extract: %s", filepath.Join(parentDir, filepath.Dir(files), info.Name()))
-					}
-					a.Extract(filepath.Join(parentDir, filepath.Dir(files)))
-
-				}
-				return nil
-			})
-		if err != nil {
-			return err
-		}
+// neFromDumpName returns the NE of a downloaded file named
+// <ftpname>_<date>[_<localname>][_<n>].<ext>.
+func neFromDumpName(name, currentDate string) (string, bool) {
+	idx := strings.Index(name, "_"+currentDate)
+	if idx < 1 {
+		return "", false
 	}
-	return nil
+	return name[:idx], true
 }
 
-func listAccessLocation(location string) map[string]string {
+// listAccessLocation maps every result folder holding dumps to parse,
+// downloaded for one of neNames or loose .txt files, to its absolute path.
+func listAccessLocation(location string, currentDate string, neNames map[string]bool) map[string]string {
 	accessDestination := make(map[string]string)
 	parentDir, _ := os.Getwd()
 	err := filepath.Walk(location,
 		func(files string, info os.FileInfo, err error) error {
 			if err != nil {
 				log.Error(err)
+				return nil
 			}
-			if path.Ext(info.Name()) == ".txt" {
+			if info.IsDir() && (info.Name() == stagingDir || info.Name() == quarantineDir || info.Name() == fragmentDir || info.Name() == "_dumpresult") {
+				return filepath.SkipDir
+			}
+			if path.Ext(info.Name()) == ".txt" || isRawDump(info, currentDate, neNames) {
 				if _, ok := accessDestination[filepath.Base(filepath.Dir(files))]; !ok {
 					accessDestination[filepath.Base(filepath.Dir(files))] = filepath.Join(parentDir, filepath.Dir(files))
 				}
@@ -710,289 +1141,1015 @@ func listAccessLocation(location string) map[string]string {
 	return accessDestination
 }
 
-func MainProcess(sourceDir string, resultDir string, skipDoubleSlash bool, techNeName string, isAccess, keepCSV bool, dbName string, isLogOut bool, wg *sync.WaitGroup, nationalPart map[string][]string, currentDate string, ftpConfigs []configs.Config, mapConfig map[string]string) {
-	defer wg.Done()
-	tables := make(map[string]*configs.Table)
+// dumpFile is one dump to parse, an archive or a plain text file, and the
+// NE it belongs to. NeName is empty for loose files whose NE has to come
+// from the file name.
+type dumpFile struct {
+	Path   string
+	NeName string
+}
+
+// listDumps returns the dumps downloaded into sourceDir for neNames followed
+// by its other loose .txt files.
+func listDumps(sourceDir, currentDate string, neNames map[string]bool) ([]dumpFile, error) {
 	files, err := ioutil.ReadDir(sourceDir)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	// var storeNeName string
-	for _, file := range files {
-
-		if path.Ext(file.Name()) == ".txt" {
-			// Split File Name to Value from mapConfig --> CFGMML-RNC1091-10.5.99.18
-			nameSplit := strings.Split(file.Name(), "-")
-			checkName := strings.TrimSpace(fmt.Sprintf("%s-%s-%s", nameSplit[0], nameSplit[1], nameSplit[2]))
-			if _, ok := mapConfig[checkName]; !ok {
-				log.Errorf("Fail to get NeName from Config from file %s", file.Name())
-				return
-			}
-			neName := mapConfig[checkName]
 
-			fullName := filepath.Join(sourceDir, file.Name())
-			log.Infof("Processing: %s", fullName)
-			f, err := os.Open(fullName)
-			if err != nil {
-				log.Errorf("Error Open File: %s", fullName)
-				return
+	var dumps, loose []dumpFile
+	for _, file := range files {
+		if isRawDump(file, currentDate, neNames) {
+			neName, _ := neFromDumpName(file.Name(), currentDate)
+			dumps = append(dumps, dumpFile{Path: filepath.Join(sourceDir, file.Name()), NeName: neName})
+			continue
+		}
+		if !file.IsDir() && path.Ext(file.Name()) == ".txt" {
+			if _, ok := neFromDumpName(file.Name(), currentDate); !ok {
+				loose = append(loose, dumpFile{Path: filepath.Join(sourceDir, file.Name())})
 			}
-			// neName := ""
-
-			scanner := bufio.NewScanner(f)
-			it := 1
-			for scanner.Scan() {
-				it++
-				if it < 10 {
-					continue
-				}
-				line := scanner.Text()
-				if strings.TrimSpace(line) == "" {
-					continue
-				}
+		}
+	}
+	return append(dumps, loose...), nil
+}
 
-				if line[:2] == "//" && skipDoubleSlash {
-					continue
-				}
+// parseDumps parses every dump into a fragment of its own, on workers
+// goroutines, the fragments in the order of dumps.
+func parseDumps(dumps []dumpFile, ctx *parseContext, workers int, keepCSV bool, limits extractLimits, ftpConfigs []configs.Config, mapConfig map[string]string, manifest *downloadManifest) []*parseContext {
+	neConfigs := make(map[string]configs.Config)
+	for _, c := range ftpConfigs {
+		neConfigs[c.FtpName] = c
+	}
 
-				if !skipDoubleSlash {
-					line = strings.ReplaceAll(scanner.Text(), "//", "")
+	parseFile := func(i int) *parseContext {
+		dump := dumps[i]
+		frag := ctx.fragment(filepath.Join(ctx.ResultDir, fmt.Sprintf("%06d", i)))
+		if err := os.MkdirAll(frag.ResultDir, 0755); err != nil {
+			log.Errorf("Cannot Create Folder: %s Err: %s", frag.ResultDir, err.Error())
+			return frag
+		}
+		defer frag.closeTables()
+
+		log.Infof("Processing: %s", dump.Path)
+		cfg := neConfigs[dump.NeName]
+		err := walkArchive(dump.Path, limits, cfg.Include, cfg.Exclude, func(member string, r io.Reader) error {
+			// readme and log files travel along with the export
+			br := bufio.NewReaderSize(r, mmlSniffLen)
+			head, _ := br.Peek(mmlSniffLen)
+			if !isMMLDump(head) {
+				log.Infof("Skipping Non CFGMML Member: %s In: %s", member, dump.Path)
+				return nil
+			}
+			r = br
+
+			// Downloaded dumps belong to the NE in their name, loose files matching
+			// a config by prefix too, the others are named by their content
+			neName := dump.NeName
+			if neName == "" {
+				// Split File Name to Value from mapConfig --> CFGMML-RNC1091-10.5.99.18
+				nameSplit := strings.Split(filepath.Base(member), "-")
+				if len(nameSplit) >= 3 {
+					checkName := strings.TrimSpace(fmt.Sprintf("%s-%s-%s", nameSplit[0], nameSplit[1], nameSplit[2]))
+					neName = mapConfig[checkName]
 				}
+			}
 
-				arrStr := strings.Split(line, ":")
-				if len(arrStr) < 2 {
-					continue
+			if keepCSV && dump.NeName != "" {
+				// keep what was parsed for checking, like the CSV
+				out, err := createMember(filepath.Join(filepath.Dir(dump.Path), stagingDir, neName, strings.TrimSuffix(filepath.Base(dump.Path), filepath.Ext(dump.Path))), member)
+				if err != nil {
+					return err
 				}
+				defer out.Close()
+				r = io.TeeReader(r, out)
+			}
+			return parseDump(r, neName, path.Join(filepath.Base(dump.Path), member), frag)
+		})
+		if errors.Is(err, errUnknownNE) && dump.NeName == "" {
+			log.Errorf("Cannot Identify NE Of: %s, Moving To %s", dump.Path, quarantineDir)
+			if err := quarantine(dump.Path, err.Error()); err != nil {
+				log.Errorf("Cannot Quarantine: %s Err: %s", dump.Path, err.Error())
+			}
+			return frag
+		}
+		if err != nil {
+			log.Errorf("Cannot Extract: %s For: %s Err: %s", dump.Path, dump.NeName, err.Error())
+			if dump.NeName != "" {
+				manifest.extractFailed(dump.NeName, filepath.Base(dump.Path)+": "+err.Error())
+			}
+		}
+		return frag
+	}
 
-				tblName := strings.TrimSpace(arrStr[0])
-				if len(tblName) < 1 {
-					continue
-				}
+	if workers < 1 {
+		workers = 1
+	}
+	fragments := make([]*parseContext, len(dumps))
+	jobs := make(chan int)
+	var workerWg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		workerWg.Add(1)
+		go func() {
+			defer workerWg.Done()
+			for i := range jobs {
+				fragments[i] = parseFile(i)
+			}
+		}()
+	}
+	for i := range dumps {
+		jobs <- i
+	}
+	close(jobs)
+	workerWg.Wait()
+	return fragments
+}
 
-				if _, ok := tables[tblName]; !ok {
-					table, err := MakeNewTable(tblName, resultDir)
-					if err != nil {
-						panic(err)
-					}
+// MainProcess assembles the tables of a result folder into resultDir from
+// the fragments of its dumps, in their order so the result does not depend
+// on the workers, and fills tables with them.
+func MainProcess(resultDir string, fragments []*parseContext, base *parseContext, paramAlias bool, tables map[string]*configs.Table, wg *sync.WaitGroup) {
+	defer wg.Done()
+	ctx := base.fragment(resultDir)
+	ctx.Tables = tables
 
-					tables[tblName] = table
-				}
+	parts := mergeFragments(ctx, fragments)
+	if ctx.Dict != nil {
+		if err := writeParamDict(ctx, paramAlias); err != nil {
+			log.Errorf("Cannot Write %s: %s", paramDictTable, err.Error())
+		}
+		if table, ok := tables[paramDictTable]; ok {
+			if err := closeTable(table); err != nil {
+				log.Errorf("Cannot Write %s: %s", paramDictTable, err.Error())
+			}
+			cols := make([]int64, len(table.Header))
+			for i := range cols {
+				cols[i] = int64(i)
+			}
+			parts[paramDictTable] = []tablePart{{table: table, cols: cols}}
+			defer os.Remove(table.Fpath + spoolExt)
+		}
+	}
+	for _, table := range tables {
+		if err := writeMerged(table, parts[table.Name]); err != nil {
+			log.Errorf("Error Writing File: %s Err: %s", table.Fpath, err.Error())
+			return
+		}
+	}
 
-				table := tables[tblName]
-				arrStr[1] = strings.ReplaceAll(arrStr[1], ";", "")
-				keyVals := strings.Split(arrStr[1], ",")
-				isSubKey := false
-				row := make([]string, len(table.Header))
-				for _, kv := range keyVals {
-					keyVal := strings.Split(kv, "=")
-					key := strings.TrimSpace(keyVal[0])
-					val := ""
-
-					// Check if Val is Concatenated Param
-					// HSPAPLUSSWITCH=
-					// 64QAM-1
-					// &MIMO-0
-					// &E_FACH-0
-					// &DTX_DRX-0
-					// &HS_SCCH_LESS_OPERATION-0
-					// &DL_L2ENHANCED-1
-					// &64QAM_MIMO-0
-					// &UL_16QAM-1
-					// &DC_HSDPA-0
-					// &UL_L2ENHANCED-1
-					// &EDPCCH_BOOSTING-0
-					// &DCMIMO_HSDPA-0
-					// &E_DRX-0
-					// &DC_HSUPA-0
-					// &HSDPA_4C_MIMO-0
-					// &HSDPA_4C-0
-					// &DBMIMO_HSDPA-0
-					// &DB_HSDPA-0
-					// &HSDPA_SFDC-0
-					// &HSDPA_DF3C-0
-					// &INTERNBDB_HSDPA-0
-					// &FDPCH_CAPABILITY_INVALID-0,
-
-					if len(keyVal) > 1 {
-						val = keyVal[1]
-
-						// Check if thereis Sub Value with & But Not Col Remark & But Not Col CELLNAME Due to i.e."604360_CL&T_073_3G-1"
-						if strings.Contains(val, "&") && key != "REMARK" && !strings.Contains(key, "CELLNAME") {
-							isSubKey = true
-
-							cKeyVal := strings.Split(val, "&")
-							for _, cV := range cKeyVal {
-								subKeyVal := strings.Split(cV, "-")
-								if len(subKeyVal) > 1 {
-
-									subKey := fmt.Sprintf("%s_%v", key, strings.TrimSpace(subKeyVal[0]))
-									subVal := fmt.Sprintf("%v", subKeyVal[1])
-
-									if idx, ok := table.HeaderMap[subKey]; !ok {
-										table.HeaderMap[subKey] = int64(len(table.Header))
-										table.Header = append(table.Header, subKey)
-
-										row = append(row, subVal)
-
-									} else {
-										row[idx] = subVal
-									}
-
-								}
-
-							}
-
-						} else {
-							isSubKey = false
-						}
+	// TODO: UNTIL THIS PART --> CSV ALREADY COMBINED --> LOOP TABLESS
+	for _, table := range tables {
+		if len(table.Header) > maxAccessColumns {
+			if err := splitTable(table); err != nil {
+				log.Errorf("Error Splitting File: %s Err: %s", table.Fpath, err.Error())
+				return
+			}
+		} else {
+			table.ListFile = append(table.ListFile, fmt.Sprintf("%s.csv", table.Name))
+			table.TableName = append(table.TableName, table.Name)
+			table.ListColumns = append(table.ListColumns, table.Header)
+		}
+	}
 
-						if len(val) > 2 && val[:2] == "H'" {
-							output, err := strconv.ParseInt(hexaNumberToInteger(val[2:]), 16, 64)
-							if err != nil {
-								logStd.Println(err)
-							}
-							val = fmt.Sprintf("%v", output)
-						}
-						// SFXX to Access Get Converted into $ Currency -- Store as Text
-						if len(val) > 2 && val[:2] == "SF" {
-							val = fmt.Sprintf("%q", val)
-						}
+	if err := writeSchemas(tables, resultDir); err != nil {
+		log.Errorf("Cannot Write Schema: %s Err: %s", resultDir, err.Error())
+	}
+}
 
-					}
+// fragmentDir holds the tables of every dump while the result folders are
+// assembled, see mergeFragments.
+const fragmentDir = "_fragments"
+
+// fragment returns a context parsing into tables of its own under dir.
+func (ctx *parseContext) fragment(dir string) *parseContext {
+	frag := *ctx
+	frag.ResultDir = dir
+	frag.Tables = make(map[string]*configs.Table)
+	frag.DictSeen = make(map[string]map[string]configs.ParamInfo)
+	return &frag
+}
 
-					if !isSubKey {
-						if idx, ok := table.HeaderMap[key]; !ok {
-							table.HeaderMap[key] = int64(len(table.Header))
-							table.Header = append(table.Header, key)
+// closeTables flushes and closes the spools of the tables.
+func (ctx *parseContext) closeTables() {
+	for _, table := range ctx.Tables {
+		if err := closeTable(table); err != nil {
+			log.Errorf("Error Writing File: %s Err: %s", table.Fpath+spoolExt, err.Error())
+		}
+	}
+}
 
-							row = append(row, val)
+func closeTable(table *configs.Table) error {
+	table.Rows.Flush()
+	if err := table.Rows.Error(); err != nil {
+		return err
+	}
+	return table.File.Close()
+}
 
-						} else {
-							row[idx] = val
-						}
-					}
+// tablePart is the table of one fragment and, for each of its columns, the
+// column of the merged table it goes to. The columns are placed before
+// aliasColumn renames any of them.
+type tablePart struct {
+	table *configs.Table
+	cols  []int64
+}
 
+// mergeFragments unites the tables of the fragments into ctx.Tables, to be
+// written by writeMerged, and returns the fragment tables of every table in
+// the order of the fragments. Columns are ordered as they first show up in
+// that order, types and seen dictionary entries are merged.
+func mergeFragments(ctx *parseContext, fragments []*parseContext) map[string][]tablePart {
+	parts := make(map[string][]tablePart)
+	for _, frag := range fragments {
+		if frag == nil {
+			continue
+		}
+		names := make([]string, 0, len(frag.Tables))
+		for name := range frag.Tables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			part := frag.Tables[name]
+			table, ok := ctx.Tables[name]
+			if !ok {
+				table = &configs.Table{
+					Name:      name,
+					Fpath:     filepath.Join(ctx.ResultDir, name+".csv"),
+					HeaderMap: make(map[string]int64),
 				}
-
-				content := append([]byte(neName), []byte(strings.Join(row, ",")+"\n")...)
-				if _, err := table.File.Write(content); err != nil {
-					panic(err)
+				ctx.Tables[name] = table
+			}
+			cols := make([]int64, len(part.Header))
+			for i, h := range part.Header {
+				idx, ok := table.HeaderMap[h]
+				if !ok {
+					idx = int64(len(table.Header))
+					table.HeaderMap[h] = idx
+					table.Header = append(table.Header, h)
+					table.Types = append(table.Types, "")
+				}
+				if i < len(part.Types) {
+					table.Types[idx] = configs.MergeType(table.Types[idx], part.Types[i])
 				}
+				cols[i] = idx
+			}
+			parts[name] = append(parts[name], tablePart{table: part, cols: cols})
+		}
 
+		for name, seen := range frag.DictSeen {
+			if ctx.DictSeen[name] == nil {
+				ctx.DictSeen[name] = make(map[string]configs.ParamInfo)
+			}
+			for p, info := range seen {
+				ctx.DictSeen[name][p] = info
 			}
+		}
+	}
+	return parts
+}
 
+// writeMerged writes the CSV of table, its header and the rows spooled in
+// parts, one after the other. Every row gets the columns of the header, the
+// values are placed by the columns of their part.
+func writeMerged(table *configs.Table, parts []tablePart) error {
+	out, err := os.Create(table.Fpath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	w := csv.NewWriter(out)
+	if err := w.Write(table.Header); err != nil {
+		return err
+	}
+	row := make([]string, len(table.Header))
+	for _, part := range parts {
+		if err := copyPart(w, part.table.Fpath+spoolExt, part.cols, row); err != nil {
+			return err
 		}
+	}
+	w.Flush()
+	return w.Error()
+}
 
+// copyPart writes the rows of spool to w, the value in column i of a row
+// going to column cols[i] of row. The spool is kept for other folders.
+func copyPart(w *csv.Writer, spool string, cols []int64, row []string) error {
+	in, err := os.Open(spool)
+	if err != nil {
+		return err
 	}
-	for _, table := range tables {
-		defer table.File.Close()
-		content, err := os.ReadFile(table.Fpath)
+	defer in.Close()
+
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
-			log.Errorf("Error Reading File: %s", table.Fpath)
-			return
+			return err
+		}
+		for i := range row {
+			row[i] = ""
+		}
+		for i, v := range rec {
+			row[cols[i]] = v
 		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		buffer := new(bytes.Buffer)
-		buffer.Write([]byte(strings.Join(table.Header, ",") + "\n"))
-		buffer.Write(content)
+// splitTable spreads a table wider than Access allows over <name>_<n>.csv,
+// every file repeating the key columns.
+func splitTable(table *configs.Table) error {
+	// NE NAME, DUMP DATE, VERB and IS_COMMENTED are repeated in every split
+	keyC := 2
+	for _, c := range []string{verbColumn, isCommentedColumn} {
+		if _, ok := table.HeaderMap[c]; ok {
+			keyC++
+		}
+	}
+	// TEMPORARY --> UNTIL NOW ONLY THIS MEAS GROUP FOR CELL LEVEL -> GET NE NAME AND CELLID FOR EACH SPLIT
+	if strings.Contains(table.Name, "UCELLCOALGOENHPARA") {
+		keyC++
+	}
+	perFile := maxAccessColumns - keyC
+	noSplitFile := int(math.Ceil(float64(len(table.Header)-keyC) / float64(perFile)))
+
+	// columns of split n from a row of the table
+	split := func(r []string, n int) []string {
+		minC := keyC + n*perFile
+		maxC := minC + perFile
+		if maxC > len(r) {
+			maxC = len(r)
+		}
+		return append(append([]string(nil), r[:keyC]...), r[minC:maxC]...)
+	}
 
-		if err := os.WriteFile(table.Fpath, buffer.Bytes(), 0666); err != nil {
-			log.Errorf("Error Writing File: %s", table.Fpath)
-			return
+	dir := filepath.Dir(table.Fpath)
+	writers := make([]*csv.Writer, noSplitFile)
+	for n := range writers {
+		name := fmt.Sprintf("%s_%v.csv", table.Name, n+1)
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return err
 		}
+		defer f.Close()
+		writers[n] = csv.NewWriter(f)
 
+		table.ListFile = append(table.ListFile, name)
+		table.TableName = append(table.TableName, fmt.Sprintf("%s %v", table.Name, n+1))
+		table.ListColumns = append(table.ListColumns, split(table.Header, n))
 	}
 
-	// TODO: UNTIL THIS PART --> CSV ALREADY COMBINED --> LOOP TABLESS
-	for _, table := range tables {
-
-		if len(table.Header) > 255 {
-			f, err := os.Open(table.Fpath)
-			if err != nil {
-				log.Errorf("Error Opening File: %s", table.Fpath)
-				return
+	f, err := os.Open(table.Fpath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.ReuseRecord = true
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		for n, w := range writers {
+			if err := w.Write(split(rec, n)); err != nil {
+				return err
 			}
+		}
+	}
+	for _, w := range writers {
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mmlSniffLen is how much of a member is searched for a first MML command,
+// past the header of the export.
+const mmlSniffLen = 64 << 10
+
+var mmlCommand = regexp.MustCompile(`(?m)^\s*(//)?\s*[A-Z]{2,}\s+[A-Z][A-Z0-9_]*\s*:`)
+
+// isMMLDump reports whether head, the start of a text file, holds MML
+// commands like "ADD UCELLSETUP:".
+func isMMLDump(head []byte) bool {
+	return mmlCommand.Match(head)
+}
+
+// Handling of commands commented out with a leading //:
+//
+//	skip   leave them out
+//	strip  parse them like the others
+//	flag   parse them and tell them apart in the IS_COMMENTED column
+const (
+	commentSkip  = "skip"
+	commentStrip = "strip"
+	commentFlag  = "flag"
+)
+
+// isCommentedColumn is 1 for commented commands under commentFlag, else 0.
+const isCommentedColumn = "IS_COMMENTED"
+
+// verbColumn holds the verb of each row, except under verbModeSeparate.
+const verbColumn = "VERB"
+
+const (
+	verbModeAdd      = "add"
+	verbModeMerge    = "merge"
+	verbModeSeparate = "separate"
+)
+
+// tableName returns the table of rec under verbMode, empty when the record
+// is left out. Tables are named after the MO, the verb goes to the VERB
+// column:
+//
+//	add       only ADD, in <MO>
+//	merge     ADD and MOD, in <MO>, also when verbMode is empty
+//	separate  every verb in its own <VERB>_<MO>, without VERB column
+//
+// Commands without MO, such as LST VERSION, keep their own table.
+func tableName(rec *mml.Record, verbMode string) string {
+	if rec.Object == "" {
+		return rec.Command
+	}
+	switch verbMode {
+	case verbModeAdd:
+		if rec.Command != "ADD" {
+			return ""
+		}
+	case verbModeMerge, "":
+		if rec.Command != "ADD" && rec.Command != "MOD" {
+			return ""
+		}
+	case verbModeSeparate:
+		return rec.Command + "_" + rec.Object
+	}
+	return rec.Object
+}
+
+// parseContext is what parseDump needs to know about the run.
+type parseContext struct {
+	ResultDir   string
+	CommentMode string
+	VerbMode    string
+	CurrentDate string
+	// DumpDates maps NE to the date of its dump, CurrentDate when missing.
+	DumpDates map[string]string
+	// NeAliases maps the NE names found in dumps to configured NEs.
+	NeAliases map[string]string
+	Rules     configs.ParamRules
+	// Dict is the parameter dictionary, nil without one. DictSeen holds the
+	// entries of the parameters found, by table and parameter.
+	Dict     configs.ParamDict
+	DictSeen map[string]map[string]configs.ParamInfo
+	// Report collects what could not be parsed cleanly, nil ignores it.
+	Report *parseReport
+	Tables map[string]*configs.Table
+}
+
+// errUnknownNE is returned for a dump whose NE cannot be told.
+var errUnknownNE = errors.New("no NE name in config, dump header or SET SYS")
+
+// parseDump reads the records of one CFGMML dump of neName from r and
+// appends them to the CSV of their table, creating tables in the result
+// folder as they come. The dump itself is listed in _DUMP_INFO, source
+// names it there. Without neName the NE comes from the dump, see
+// neFromContent, and errUnknownNE is returned before anything is written
+// when it has none.
+func parseDump(r io.Reader, neName, source string, ctx *parseContext) error {
+	resultDir, verbMode, tables := ctx.ResultDir, ctx.VerbMode, ctx.Tables
+
+	mr := mml.NewReader(r)
+	rec, err := mr.Next()
+	if neName == "" && (err == nil || err == io.EOF) {
+		neName = neFromContent(mr.Header(), rec, ctx.NeAliases)
+		if neName == "" {
+			return fmt.Errorf("%s: %w", source, errUnknownNE)
+		}
+	}
+	dumpDate, ok := ctx.DumpDates[neName]
+	if !ok {
+		dumpDate = ctx.CurrentDate
+	}
 
-			defer f.Close()
-			csvReader := csv.NewReader(f)
-			csvReader.FieldsPerRecord = -1
-			csvReader.LazyQuotes = true
-			line, err := csvReader.ReadAll()
+	for ; err == nil; rec, err = mr.Next() {
+		reportSkipped(mr, neName, source, ctx.Report)
+		if rec.Commented && ctx.CommentMode == commentSkip {
+			continue
+		}
+
+		tblName := tableName(rec, verbMode)
+		if tblName == "" {
+			continue
+		}
+		for _, p := range rec.Problems {
+			ctx.Report.addLine(neName, source, tblName, p.Param, p.Line, p.Kind, p.Text)
+		}
+		if _, ok := tables[tblName]; !ok {
+			table, err := MakeNewTable(tblName, resultDir)
 			if err != nil {
-				log.Errorf("Error ReadAll File: %s || %s", err.Error(), f.Name())
+				return err
 			}
-
-			noSplitFile := int(math.Ceil(float64(len(table.Header)) / 255))
-			for n := 1; n <= noSplitFile; n++ {
-				table.ListFile = append(table.ListFile, fmt.Sprintf("%s_%v.csv", table.Name, n))
-				table.TableName = append(table.TableName, fmt.Sprintf("%s %v", table.Name, n))
+			if verbMode == verbModeSeparate {
+				// the verb is in the table name already
+				table.Header = table.Header[:2]
+				table.Types = table.Types[:2]
+				delete(table.HeaderMap, verbColumn)
+			}
+			if ctx.CommentMode == commentFlag {
+				setColumn(table, nil, isCommentedColumn, "")
 			}
 
-			dir := filepath.Dir(table.Fpath)
+			tables[tblName] = table
+		}
 
-			minC := 1
-			maxC := 254
-			// TEMPORARY --> UNTIL NOW ONLY THIS MEAS GROUP FOR CELL LEVEL -> GET NE NAME AND CELLID FOR EACH SPLIT
-			if strings.Contains(table.Name, "UCELLCOALGOENHPARA") {
-				minC = 2
+		table := tables[tblName]
+		row := make([]string, len(table.Header))
+		row[1] = dumpDate
+		params := 2
+		if idx, ok := table.HeaderMap[verbColumn]; ok {
+			row[idx] = rec.Command
+			params++
+		}
+		if idx, ok := table.HeaderMap[isCommentedColumn]; ok {
+			row[idx] = "0"
+			if rec.Commented {
+				row[idx] = "1"
 			}
-			for i, nf := range table.ListFile {
-				csvFile, err := os.Create(filepath.Join(dir, nf))
-				if err != nil {
-					log.Fatalf("failed creating file: %s", err)
-				}
-				defer csvFile.Close()
-
-				csvwriter := csv.NewWriter(csvFile)
-				defer csvwriter.Flush()
-				var data [][]string
-				if i == len(table.ListFile)-1 {
-
-					for _, r := range line {
-						// TEMPORARY --> UNTIL NOW ONLY THIS MEAS GROUP FOR CELL LEVEL -> GET NE NAME AND CELLID FOR EACH SPLIT
-						if strings.Contains(table.Name, "UCELLCOALGOENHPARA") {
-							row := append([]string{r[0], r[1]}, r[minC:]...)
-							data = append(data, row)
-						} else {
-							row := append([]string{r[0]}, r[minC:]...)
-							data = append(data, row)
+		}
+		for _, p := range rec.Params {
+			key := p.Name
+			val := p.Value
+
+			hexVal := ""
+			if len(val) > 2 && val[:2] == "H'" {
+				format := ctx.Rules.HexFormat(rec.Object, key)
+				if format != configs.HexAsIs {
+					output, ok := new(big.Int).SetString(hexaNumberToInteger(val[2:]), 16)
+					if !ok {
+						ctx.Report.add(neName, source, table.Name, key, rec.Line, reportBadHex, "kept as is: "+val)
+					} else {
+						if format == configs.HexBoth {
+							hexVal = val
 						}
+						val = output.String()
 					}
-				} else {
-					for _, r := range line {
-
-						if (len(r)) < maxC {
-							maxC = len(r)
-						}
-						if strings.Contains(table.Name, "UCELLCOALGOENHPARA") {
-							row := append([]string{r[0], r[1]}, r[minC:maxC]...)
-							data = append(data, row)
-						} else {
-							row := append([]string{r[0]}, r[minC:maxC]...)
-							data = append(data, row)
-						}
+				}
+			}
+			if ctx.Dict != nil {
+				mo := rec.Object
+				if mo == "" {
+					mo = rec.Command
+				}
+				if info, ok := ctx.Dict.Lookup(mo, key); ok {
+					if ctx.DictSeen[table.Name] == nil {
+						ctx.DictSeen[table.Name] = make(map[string]configs.ParamInfo)
 					}
+					ctx.DictSeen[table.Name][key] = info
+				} else {
+					ctx.Report.add(neName, source, table.Name, key, rec.Line, reportNotInDict, "no entry for "+mo+"."+key)
 				}
+			}
 
-				csvwriter.WriteAll(data)
+			row = setColumn(table, row, key, val)
+			if hexVal != "" {
+				row = setColumn(table, row, key+"_HEX", hexVal)
+			}
 
-				minC = maxC
-				maxC = maxC + 254
+			// Check if Val is Concatenated Param
+			// HSPAPLUSSWITCH=
+			// 64QAM-1
+			// &MIMO-0
+			// &E_FACH-0
+			// &DTX_DRX-0
+			// &HS_SCCH_LESS_OPERATION-0
+			// &DL_L2ENHANCED-1
+			// &64QAM_MIMO-0
+			// &UL_16QAM-1
+			// &DC_HSDPA-0
+			// &UL_L2ENHANCED-1
+			// &EDPCCH_BOOSTING-0
+			// &DCMIMO_HSDPA-0
+			// &E_DRX-0
+			// &DC_HSUPA-0
+			// &HSDPA_4C_MIMO-0
+			// &HSDPA_4C-0
+			// &DBMIMO_HSDPA-0
+			// &DB_HSDPA-0
+			// &HSDPA_SFDC-0
+			// &HSDPA_DF3C-0
+			// &INTERNBDB_HSDPA-0
+			// &FDPCH_CAPABILITY_INVALID-0,
+			// Quoted Text is Never Expanded Due to i.e. CELLNAME="604360_CL&T_073_3G-1"
+			if p.Value != "" && !strings.HasPrefix(p.Value, `"`) {
+				row = expandSwitch(table, row, rec, key, p.Value, neName, source, ctx)
 			}
+		}
 
-		} else {
-			table.ListFile = append(table.ListFile, fmt.Sprintf("%s.csv", table.Name))
-			table.TableName = append(table.TableName, table.Name)
+		table.InferRow(row, params)
+		row[0] = neName
+		for i, v := range row {
+			row[i] = mml.Unquote(v)
+		}
+		if err := table.Rows.Write(row); err != nil {
+			return err
+		}
+
+	}
+	reportSkipped(mr, neName, source, ctx.Report)
+	if err != io.EOF {
+		return err
+	}
+	return writeDumpInfo(tables, resultDir, neName, dumpDate, source, mr)
+}
+
+// reportSkipped reports the lines mr skipped so far.
+func reportSkipped(mr *mml.Reader, neName, source string, report *parseReport) {
+	for _, p := range mr.Problems() {
+		report.addLine(neName, source, "", p.Param, p.Line, p.Kind, p.Text)
+	}
+}
+
+// loadParamRules reads the parameter rules file, the defaults are used when
+// there is none.
+func loadParamRules(fpath string) configs.ParamRules {
+	if fpath == "" {
+		return configs.DefaultParamRules
+	}
+	c, err := ioutil.ReadFile(fpath)
+	if os.IsNotExist(err) {
+		log.Warnf("No Parameter Rules Found: %s, Using Defaults", fpath)
+		return configs.DefaultParamRules
+	}
+	if err != nil {
+		panic(err)
+	}
+	var rules configs.ParamRules
+	if err := json.Unmarshal(c, &rules); err != nil {
+		log.Fatalf("Invalid Parameter Rules In %s: %s", fpath, err.Error())
+	}
+	if err := rules.Validate(); err != nil {
+		log.Fatalf("Invalid Parameter Rules In %s: %s", fpath, err.Error())
+	}
+	return rules
+}
+
+// loadParamDict reads the parameter dictionary, a JSON list of entries or a
+// CSV file with the columns MO, PARAMETER, FULL NAME, UNIT, VALUE RANGE and
+// ENUM MEANINGS. Without a file there is no dictionary.
+func loadParamDict(fpath string) configs.ParamDict {
+	if fpath == "" {
+		return nil
+	}
+	c, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		log.Fatalf("Cannot Read Parameter Dictionary: %s", err.Error())
+	}
+
+	var entries []configs.ParamInfo
+	if strings.EqualFold(filepath.Ext(fpath), ".json") {
+		if err := json.Unmarshal(c, &entries); err != nil {
+			log.Fatalf("Invalid Parameter Dictionary In %s: %s", fpath, err.Error())
+		}
+		return configs.NewParamDict(entries)
+	}
+
+	r := csv.NewReader(bytes.NewReader(c))
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil || len(rows) == 0 {
+		log.Fatalf("Invalid Parameter Dictionary In %s: %v", fpath, err)
+	}
+	cols := make(map[string]int)
+	for i, h := range rows[0] {
+		cols[strings.ToUpper(strings.TrimSpace(h))] = i
+	}
+	if _, ok := cols["PARAMETER"]; !ok {
+		log.Fatalf("Invalid Parameter Dictionary In %s: No PARAMETER Column", fpath)
+	}
+	get := func(row []string, col string) string {
+		if i, ok := cols[col]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	for _, row := range rows[1:] {
+		entries = append(entries, configs.ParamInfo{
+			MO:       get(row, "MO"),
+			Param:    get(row, "PARAMETER"),
+			FullName: get(row, "FULL NAME"),
+			Unit:     get(row, "UNIT"),
+			Range:    get(row, "VALUE RANGE"),
+			Enums:    get(row, "ENUM MEANINGS"),
+		})
+	}
+	return configs.NewParamDict(entries)
+}
+
+// expandSwitch adds a <KEY>_<BIT> column for every bit of a switch value
+// such as 64QAM-1&MIMO-0, bits split from their value on the last hyphen.
+// Which parameters are switches comes from ctx.Rules; compound values of
+// parameters no rule lists are guessed from their look and reported.
+func expandSwitch(table *configs.Table, row []string, rec *mml.Record, key, val, neName, source string, ctx *parseContext) []string {
+	switch ctx.Rules.Kind(rec.Object, key) {
+	case configs.ParamNever, configs.ParamList:
+		return row
+	case "":
+		if !strings.Contains(val, "&") {
+			return row
+		}
+		if !looksLikeSwitch(val) {
+			ctx.Report.add(neName, source, table.Name, key, rec.Line, reportUnlistedList, "kept packed: "+val)
+			return row
+		}
+		ctx.Report.add(neName, source, table.Name, key, rec.Line, reportUnlistedSwitch, "expanded: "+val)
+	}
+
+	for _, bit := range strings.Split(val, "&") {
+		i := strings.LastIndex(bit, "-")
+		if i <= 0 || i == len(bit)-1 {
+			ctx.Report.add(neName, source, table.Name, key, rec.Line, reportBadSwitchBit, "no bit value in: "+bit)
+			continue
+		}
+		row = setColumn(table, row, key+"_"+strings.TrimSpace(bit[:i]), strings.TrimSpace(bit[i+1:]))
+	}
+	return row
+}
+
+// looksLikeSwitch reports whether every part of a compound value ends in a
+// hyphen and a number, like 64QAM-1&MIMO-0.
+func looksLikeSwitch(val string) bool {
+	for _, bit := range strings.Split(val, "&") {
+		i := strings.LastIndex(bit, "-")
+		if i <= 0 {
+			return false
+		}
+		if _, err := strconv.Atoi(strings.TrimSpace(bit[i+1:])); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// setColumn puts val in the column key of row and returns row, the column
+// is added to table when new.
+func setColumn(table *configs.Table, row []string, key, val string) []string {
+	if idx, ok := table.HeaderMap[key]; ok {
+		row[idx] = val
+		return row
+	}
+	table.HeaderMap[key] = int64(len(table.Header))
+	table.Header = append(table.Header, key)
+	return append(row, val)
+}
+
+// neFromContent names the NE of a dump after the NE Name of its header or
+// else the SYSOBJECTID of a leading SET SYS, mapped through aliases when
+// configured. It is empty when the dump carries neither.
+func neFromContent(h mml.Header, first *mml.Record, aliases map[string]string) string {
+	name := h.Get(mml.KeyNEName)
+	if name == "" && first != nil && first.Command == "SET" && first.Object == "SYS" {
+		for _, p := range first.Params {
+			if p.Name == "SYSOBJECTID" {
+				name = mml.Unquote(p.Value)
+			}
 		}
 	}
+	if alias, ok := aliases[name]; ok {
+		return alias
+	}
+	return name
+}
 
-	allTables = tables
+// quarantineDir holds the loose dumps of a result folder that could not be
+// parsed, each next to a <name>.reason file.
+const quarantineDir = "_quarantine"
 
+// quarantine moves fpath out of the way of later runs and records why.
+func quarantine(fpath, reason string) error {
+	dir := filepath.Join(filepath.Dir(fpath), quarantineDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	dest := filepath.Join(dir, filepath.Base(fpath))
+	if err := os.Rename(fpath, dest); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dest+".reason", []byte(reason+"\n"), 0644)
 }
 
+// dumpInfoTable lists every parsed dump with the header of its export.
+const dumpInfoTable = "_DUMP_INFO"
+
+var dumpInfoHeader = []string{"NE NAME", "DUMP DATE", "HEADER NE NAME", "NE TYPE", "NE VERSION", "EXPORT TIME", "SOURCE FILE", "LINE COUNT"}
+
+// writeDumpInfo adds the row of a dump read completely by mr to _DUMP_INFO.
+func writeDumpInfo(tables map[string]*configs.Table, resultDir, neName, dumpDate, source string, mr *mml.Reader) error {
+	table, ok := tables[dumpInfoTable]
+	if !ok {
+		var err error
+		table, err = MakeNewTable(dumpInfoTable, resultDir)
+		if err != nil {
+			return err
+		}
+		table.Header = append([]string(nil), dumpInfoHeader...)
+		table.HeaderMap = make(map[string]int64)
+		for i, h := range table.Header {
+			table.HeaderMap[h] = int64(i)
+		}
+		table.Types = table.Types[:2]
+		tables[dumpInfoTable] = table
+	}
+
+	h := mr.Header()
+	row := []string{"", dumpDate, h.Get(mml.KeyNEName), h.Get(mml.KeyNEType), h.Get(mml.KeyNEVersion), h.Get(mml.KeyExportTime), source, strconv.Itoa(mr.Lines())}
+	table.InferRow(row, 2)
+	row[0] = neName
+	return table.Rows.Write(row)
+}
+
+// writeSchemas writes the columns and types of every table to
+// <table>.schema.json, and a schema.ini for the Access text driver, so the
+// sinks create typed columns instead of guessing from the CSV.
+func writeSchemas(tables map[string]*configs.Table, resultDir string) error {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ini := new(bytes.Buffer)
+	for _, name := range names {
+		schema := tables[name].Schema()
+		content, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(resultDir, name+".schema.json"), content, 0666); err != nil {
+			return err
+		}
+
+		types := make(map[string]string)
+		for _, c := range schema.Columns {
+			types[c.Name] = c.Type
+		}
+		for _, f := range schema.Files {
+			fmt.Fprintf(ini, "[%s]\r\nColNameHeader=True\r\nFormat=CSVDelimited\r\nMaxScanRows=0\r\n", f.File)
+			for i, c := range f.Columns {
+				fmt.Fprintf(ini, "Col%d=\"%s\" %s\r\n", i+1, c, accessType(types[c], true))
+			}
+		}
+	}
+	return os.WriteFile(filepath.Join(resultDir, "schema.ini"), ini.Bytes(), 0666)
+}
+
+// accessType returns the Access type of a column type, as written in
+// schema.ini or, with ini false, in CREATE TABLE.
+func accessType(typ string, ini bool) string {
+	switch typ {
+	case configs.TypeInteger:
+		return "Long"
+	case configs.TypeDecimal:
+		return "Double"
+	case configs.TypeText:
+		if ini {
+			return "Memo"
+		}
+		return "longtext"
+	}
+	if ini {
+		return "Text Width 255"
+	}
+	return "text(255)"
+}
+
+// accessColumns returns the column definitions of file t of table for
+// CREATE TABLE.
+func accessColumns(table *configs.Table, t int) []string {
+	types := make(map[string]string)
+	for _, c := range table.Schema().Columns {
+		types[c.Name] = c.Type
+	}
+	cols := table.Header
+	if t < len(table.ListColumns) {
+		cols = table.ListColumns[t]
+	}
+	var def []string
+	for _, c := range cols {
+		def = append(def, fmt.Sprintf(`[%s] %s`, c, accessType(types[c], false)))
+	}
+	return def
+}
+
+// paramDictTable lists the dictionary entries of the parameters found.
+const paramDictTable = "_PARAM_DICT"
+
+var paramDictHeader = []string{"TABLE", "PARAMETER", "COLUMN", "FULL NAME", "UNIT", "VALUE RANGE", "ENUM MEANINGS"}
+
+// writeParamDict adds the _PARAM_DICT table for the parameters seen with a
+// dictionary entry. With alias their columns are renamed to the full name.
+func writeParamDict(ctx *parseContext, alias bool) error {
+	table, err := MakeNewTable(paramDictTable, ctx.ResultDir)
+	if err != nil {
+		return err
+	}
+	table.Header = append([]string(nil), paramDictHeader...)
+	table.HeaderMap = make(map[string]int64)
+	for i, h := range table.Header {
+		table.HeaderMap[h] = int64(i)
+	}
+	table.Types = nil
+
+	names := make([]string, 0, len(ctx.DictSeen))
+	for name := range ctx.DictSeen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		seen := ctx.DictSeen[name]
+		params := make([]string, 0, len(seen))
+		for p := range seen {
+			params = append(params, p)
+		}
+		sort.Strings(params)
+
+		data := ctx.Tables[name]
+		for _, p := range params {
+			info := seen[p]
+			column := p
+			if alias {
+				column = aliasColumn(data, p, info.FullName)
+			}
+			row := []string{name, p, column, info.FullName, info.Unit, info.Range, info.Enums}
+			table.InferRow(row, 0)
+			if err := table.Rows.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	ctx.Tables[paramDictTable] = table
+	return nil
+}
+
+// aliasColumn renames the column of param in table to fullName, made fit
+// for an Access column name, in Header and HeaderMap. The parameter name
+// stays when the full name is empty or taken. It returns the name of the
+// column.
+func aliasColumn(table *configs.Table, param, fullName string) string {
+	i, ok := table.HeaderMap[param]
+	if !ok {
+		return param
+	}
+	alias := strings.Join(strings.Fields(strings.Map(func(r rune) rune {
+		if strings.ContainsRune(".,![]`\"", r) {
+			return ' '
+		}
+		return r
+	}, fullName)), " ")
+	if len([]rune(alias)) > 64 {
+		alias = strings.TrimSpace(string([]rune(alias)[:64]))
+	}
+	if alias == "" {
+		return param
+	}
+	for _, h := range table.Header {
+		if strings.EqualFold(h, alias) {
+			return param
+		}
+	}
+	table.Header[i] = alias
+	delete(table.HeaderMap, param)
+	table.HeaderMap[alias] = i
+	return alias
+}
+
+// spoolExt is added to the CSV of a table for the rows written while
+// parsing, before the header is known, see finishTable.
+const spoolExt = ".spool"
+
+// maxAccessColumns is the most columns an Access table holds, wider tables
+// are split, see splitTable.
+const maxAccessColumns = 255
+
 func MakeNewTable(name string, resultDir string) (*configs.Table, error) {
 	fpath := filepath.Join(resultDir, name+".csv")
-	f, err := os.Create(fpath)
+	f, err := os.Create(fpath + spoolExt)
 	if err != nil {
 		return nil, err
 	}
@@ -1000,15 +2157,41 @@ func MakeNewTable(name string, resultDir string) (*configs.Table, error) {
 	return &configs.Table{
 		Name:   name,
 		Fpath:  fpath,
-		Header: []string{"NE NAME"},
+		Header: []string{"NE NAME", "DUMP DATE", verbColumn},
 		HeaderMap: map[string]int64{
-			"NE NAME": 0,
+			"NE NAME":   0,
+			"DUMP DATE": 1,
+			verbColumn:  2,
 		},
+		// the keys are text even where they look like numbers, 20211202
+		Types:  []string{configs.TypeEnum, configs.TypeEnum, configs.TypeEnum},
 		Buffer: new(bytes.Buffer),
 		File:   f,
+		Rows:   csv.NewWriter(f),
 	}, nil
 }
 
+// textSource is the FROM of the queries loading file t of table from
+// resultDir. With listNE it keeps only the rows of those NEs, except in files
+// without an NE NAME column such as _PARAM_DICT, which go whole into every
+// part.
+func textSource(table *configs.Table, t int, resultDir string, listNE []string) string {
+	src := fmt.Sprintf(`[Text;FMT=Delimited(,);HDR=YES;DATABASE=%s].[%s] as file`, resultDir, table.ListFile[t])
+	if listNE == nil {
+		return src
+	}
+	if t < len(table.ListColumns) {
+		hasNE := false
+		for _, c := range table.ListColumns[t] {
+			hasNE = hasNE || c == "NE NAME"
+		}
+		if !hasNE {
+			return src
+		}
+	}
+	return src + fmt.Sprintf(` WHERE file.[NE NAME] IN ('%s')`, strings.Join(listNE, "', '"))
+}
+
 func ExportAccess(tables map[string]*configs.Table, dbName string, resultDir string, isLogOut bool, listNE []string) {
 	// fmt.Println(tables)
 
@@ -1025,19 +2208,15 @@ func ExportAccess(tables map[string]*configs.Table, dbName string, resultDir str
 			checkSplit := len(table.ListFile)
 
 			for t := 0; t < checkSplit; t++ {
-				qry := fmt.Sprintf(`SELECT file.* INTO [%s] FROM [Text;FMT=Delimited(,);HDR=YES;DATABASE=%s].[%s] as file where file.[NE NAME] IN (%s)`, table.TableName[t], resultDir, table.ListFile[t], "'"+strings.Join(listNE, "', '")+"'")
+				qry := fmt.Sprintf(`SELECT file.* INTO [%s] FROM %s`, table.TableName[t], textSource(table, t, resultDir, listNE))
 
 				if isLogOut {
 					log.Info(qry)
 				}
 				tx, err := db.Exec(qry)
 				if err != nil && isLogOut {
-					log.Warnf("Error Inserting %s Retry With Text Data Type", table.TableName[t])
-					createTableCol := []string{}
-
-					for _, s := range table.Header {
-						createTableCol = append(createTableCol, fmt.Sprintf(`[%s] longtext`, s))
-					}
+					log.Warnf("Error Inserting %s Retry With Schema Types", table.TableName[t])
+					createTableCol := accessColumns(table, t)
 
 					newQry := fmt.Sprintf(`CREATE TABLE [%s] (%s)`, table.TableName[t], strings.Join(createTableCol, ","))
 					_, _ = db.Exec(newQry)
@@ -1047,7 +2226,7 @@ func ExportAccess(tables map[string]*configs.Table, dbName string, resultDir str
 					if err != nil && isLogOut {
 						log.Warnf("Error Creating Table %s Maybe Already Exists, Trying to Insert Values", table.TableName[t])
 					}
-					qry := fmt.Sprintf(`INSERT INTO [%s] SELECT * FROM [Text;FMT=Delimited(,);HDR=YES;DATABASE=%s].[%s] as file WHERE file.[NE NAME] IN (%s)`, table.TableName[t], resultDir, table.ListFile[t], "'"+strings.Join(listNE, "', '")+"'")
+					qry := fmt.Sprintf(`INSERT INTO [%s] SELECT * FROM %s`, table.TableName[t], textSource(table, t, resultDir, listNE))
 					tx, err = db.Exec(qry)
 					if isLogOut {
 						log.Info(qry)
@@ -1084,12 +2263,8 @@ func ExportAccess(tables map[string]*configs.Table, dbName string, resultDir str
 				}
 				tx, err := db.Exec(qry)
 				if err != nil && isLogOut {
-					log.Warnf("Error Inserting %s Retry With Text Data Type - %s", table.TableName[t], err.Error())
-					createTableCol := []string{}
-
-					for _, s := range table.Header {
-						createTableCol = append(createTableCol, fmt.Sprintf(`[%s] longtext`, s))
-					}
+					log.Warnf("Error Inserting %s Retry With Schema Types - %s", table.TableName[t], err.Error())
+					createTableCol := accessColumns(table, t)
 
 					newQry := fmt.Sprintf(`CREATE TABLE [%s] (%s)`, table.TableName[t], strings.Join(createTableCol, ","))
 					_, _ = db.Exec(newQry)
@@ -1216,20 +2391,20 @@ func hexaNumberToInteger(hexaString string) string {
 	return numberStr
 }
 
-func GoAccess(sourceDir string, resultDir string, skipDoubleSlash bool, techNeName string, isAccess, keepCSV bool, dbName string, isLogOut bool, nationalPart map[string][]string, currentDate string, ftpConfigs []configs.Config, mapConfig map[string]string, wg2 *sync.WaitGroup) {
+func GoAccess(sourceDir string, resultDir string, skipDoubleSlash bool, techNeName string, isAccess, keepCSV bool, dbName string, isLogOut bool, nationalPart map[string][]string, currentDate string, ftpConfigs []configs.Config, mapConfig map[string]string, tables map[string]*configs.Table, wg2 *sync.WaitGroup) {
 	defer wg2.Done()
 	dbName2 := filepath.Base(dbName)[:len(filepath.Base(dbName))-len(filepath.Ext(filepath.Base(dbName)))-len(currentDate)]
 
 	// access region
 	if isAccess && !strings.Contains(dbName2, "National") {
 		logStd.Printf("Populating: %s\n", dbName)
-		ExportAccess(allTables, dbName, resultDir, false, nil)
+		ExportAccess(tables, dbName, resultDir, false, nil)
 	}
 
 	// access national all
 	if isAccess && strings.Contains(dbName2, "National") && len(nationalPart) == 0 {
 		logStd.Printf("Populating: %s\n", dbName)
-		ExportAccess(allTables, dbName, resultDir, isLogOut, nil)
+		ExportAccess(tables, dbName, resultDir, isLogOut, nil)
 	}
 
 	// access national part
@@ -1238,7 +2413,7 @@ func GoAccess(sourceDir string, resultDir string, skipDoubleSlash bool, techNeNa
 			dbNamePart := filepath.Join(filepath.Dir(dbName), dbName2+part+"_"+currentDate+".accdb")
 			logStd.Printf("Populating: %s\n", dbNamePart)
 
-			ExportAccess(allTables, dbNamePart, resultDir, isLogOut, listNe)
+			ExportAccess(tables, dbNamePart, resultDir, isLogOut, listNe)
 		}
 	}
 
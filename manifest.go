@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	statusDownloaded = "DOWNLOADED"
+	statusFallback   = "FALLBACK"
+	statusFailed     = "FAILED"
+)
+
+// manifestEntry records where the dump of one NE came from in this run.
+type manifestEntry struct {
+	FtpName  string
+	Region   string
+	Part     string
+	Status   string
+	DumpDate string
+	Source   string
+	Folder   string
+	// ExtractError is set when the downloaded dump could not be extracted.
+	ExtractError string
+}
+
+type downloadManifest struct {
+	mu      sync.Mutex
+	entries map[string]manifestEntry
+}
+
+func newDownloadManifest() *downloadManifest {
+	return &downloadManifest{entries: make(map[string]manifestEntry)}
+}
+
+func (m *downloadManifest) add(e manifestEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[e.FtpName] = e
+}
+
+// extractFailed records why the dump of ftpName could not be extracted.
+func (m *downloadManifest) extractFailed(ftpName, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[ftpName]
+	if !ok {
+		e = manifestEntry{FtpName: ftpName}
+	}
+	// region and national copies of a dump fail alike
+	if strings.Contains(e.ExtractError, reason) {
+		return
+	}
+	if e.ExtractError != "" {
+		reason = e.ExtractError + " | " + reason
+	}
+	e.ExtractError = reason
+	m.entries[ftpName] = e
+}
+
+func (m *downloadManifest) count(status string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, e := range m.entries {
+		if e.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// dumpDates maps NE name to the date of the dump actually used for it.
+func (m *downloadManifest) dumpDates() map[string]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	dates := make(map[string]string)
+	for k, e := range m.entries {
+		if e.Status != statusFailed {
+			dates[k] = e.DumpDate
+		}
+	}
+	return dates
+}
+
+func (m *downloadManifest) write(fpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.entries))
+	for k := range m.entries {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	f, err := os.Create(fpath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"NE NAME", "REGION", "PART", "STATUS", "SUBSTITUTED", "DUMP DATE", "SOURCE", "FOLDER", "EXTRACT ERROR"})
+	for _, n := range names {
+		e := m.entries[n]
+		w.Write([]string{e.FtpName, e.Region, e.Part, e.Status, strconv.FormatBool(e.Status == statusFallback), e.DumpDate, e.Source, e.Folder, e.ExtractError})
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,50 @@
+package mml
+
+import "strings"
+
+// Keys of the header fields written by the BAM.
+const (
+	KeyNEName     = "NE Name"
+	KeyNEType     = "NE Type"
+	KeyNEVersion  = "NE Version"
+	KeyExportTime = "Export Time"
+	KeyExportUser = "Export User"
+)
+
+// Header holds the "//Key: Value" lines found before the first command:
+//
+//	//NE Name: RNC_Magelang
+//	//NE Version: V900R019C10SPC500
+//	//Export Time: 2021-12-02 08:30:00
+type Header struct {
+	// Fields are the values by key, Keys the keys in script order.
+	Fields map[string]string
+	Keys   []string
+}
+
+// Get returns the value of key, empty when the header has none.
+func (h Header) Get(key string) string {
+	return h.Fields[key]
+}
+
+func (h *Header) set(key, value string) {
+	if h.Fields == nil {
+		h.Fields = make(map[string]string)
+	}
+	if _, ok := h.Fields[key]; !ok {
+		h.Keys = append(h.Keys, key)
+	}
+	h.Fields[key] = value
+}
+
+// parseHeaderLine returns the key and value of a header line.
+func parseHeaderLine(line string) (string, string, bool) {
+	if !strings.HasPrefix(line, "//") {
+		return "", "", false
+	}
+	kv := strings.SplitN(line[2:], ":", 2)
+	if len(kv) < 2 || strings.TrimSpace(kv[0]) == "" {
+		return "", "", false
+	}
+	return strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]), true
+}
@@ -0,0 +1,278 @@
+// Package mml reads the CFGMML configuration scripts exported by the Huawei
+// BAM, one record per MML command:
+//
+//	ADD UCELLSETUP:CELLID=11001,CELLNAME="MGL001_1",LAC=H'2B67;
+//
+// The banner and the "//Key: Value" header before the first command are
+// read into a Header. Values are returned as written in the script, quotes
+// included, see Unquote; interpreting them is left to the caller.
+package mml
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// maxLineLen bounds a single command, wide MOs easily pass the 64K of bufio.
+const maxLineLen = 4 << 20
+
+// Param is one NAME=VALUE of a command.
+type Param struct {
+	Name  string
+	Value string
+}
+
+// Kinds of Problem.
+const (
+	ProblemNotCommand   = "NOT A COMMAND"
+	ProblemNoValue      = "NO VALUE"
+	ProblemNoName       = "NO PARAMETER NAME"
+	ProblemDuplicate    = "DUPLICATE PARAMETER"
+	ProblemUnterminated = "UNTERMINATED QUOTE"
+	ProblemTrailingText = "TEXT AFTER COMMAND"
+)
+
+// Problem is something of the script the Reader could not read cleanly.
+// Param is set for problems with one parameter of a command.
+type Problem struct {
+	Line  int
+	Kind  string
+	Param string
+	Text  string
+}
+
+// Record is one MML command.
+type Record struct {
+	// Line is the line number of the command in the script, starting at 1.
+	Line int
+	// Command is the verb, "ADD", and Object the managed object, "UCELLSETUP".
+	Command string
+	Object  string
+	// Params are in the order of the script.
+	Params []Param
+	// Commented is set for commands disabled with a leading "//".
+	Commented bool
+	// Problems of the command, the record holds what could be read.
+	Problems []Problem
+}
+
+// Name returns the command as written before the colon, "ADD UCELLSETUP".
+func (r *Record) Name() string {
+	if r.Object == "" {
+		return r.Command
+	}
+	return r.Command + " " + r.Object
+}
+
+// Reader reads records from a CFGMML script.
+type Reader struct {
+	scanner  *bufio.Scanner
+	line     int
+	header   Header
+	commands bool
+	problems []Problem
+}
+
+// NewReader returns a Reader reading the script from r.
+func NewReader(r io.Reader) *Reader {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), maxLineLen)
+	return &Reader{scanner: s}
+}
+
+// Header returns the header of the script, complete once Next returned the
+// first record or io.EOF.
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// Lines returns the number of lines read so far.
+func (r *Reader) Lines() int {
+	return r.line
+}
+
+// Problems returns the lines skipped since the last call, see Next.
+func (r *Reader) Problems() []Problem {
+	p := r.problems
+	r.problems = nil
+	return p
+}
+
+// Next returns the next command of the script, or io.EOF after the last one.
+// Lines that are no command are skipped, header lines before the first
+// command go to the Header. Skipped lines after the first command other than
+// blank lines and // or /* comments are kept for Problems.
+func (r *Reader) Next() (*Record, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := r.scanner.Text()
+		if strings.HasPrefix(line, "/*") {
+			continue
+		}
+		if rec := parseLine(line, r.line); rec != nil {
+			r.commands = true
+			return rec, nil
+		}
+		if !r.commands {
+			// header and banner of the export
+			if key, value, ok := parseHeaderLine(line); ok {
+				r.header.set(key, value)
+			}
+			continue
+		}
+		if text := strings.TrimSpace(line); text != "" && !strings.HasPrefix(text, "//") && !strings.HasPrefix(text, "/*") {
+			r.problems = append(r.problems, Problem{Line: r.line, Kind: ProblemNotCommand, Text: text})
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// parseLine parses one command, nil when line holds none.
+//
+// Double quoted text may hold any of ,:=; and escapes quotes and
+// backslashes with a backslash. Only a ; outside quotes ends the command.
+func parseLine(line string, n int) *Record {
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+
+	rec := &Record{Line: n}
+	if strings.HasPrefix(line, "//") {
+		rec.Commented = true
+		line = line[2:]
+	}
+
+	colon := strings.IndexByte(line, ':')
+	if colon < 0 {
+		return nil
+	}
+	name := strings.Fields(line[:colon])
+	if len(name) == 0 {
+		return nil
+	}
+	for _, n := range name {
+		if !isName(n) {
+			return nil
+		}
+	}
+	rec.Command = name[0]
+	rec.Object = strings.Join(name[1:], " ")
+
+	fields, tail, inQuote := splitParams(line[colon+1:])
+	if inQuote {
+		rec.Problems = append(rec.Problems, Problem{Line: n, Kind: ProblemUnterminated, Text: strings.TrimSpace(line)})
+	}
+	if tail = strings.TrimSpace(tail); tail != "" && !strings.HasPrefix(tail, "//") {
+		rec.Problems = append(rec.Problems, Problem{Line: n, Kind: ProblemTrailingText, Text: tail})
+	}
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		p := Param{Name: strings.TrimSpace(field)}
+		if eq := indexUnquoted(field, '='); eq >= 0 {
+			p = Param{
+				Name:  strings.TrimSpace(field[:eq]),
+				Value: strings.TrimSpace(field[eq+1:]),
+			}
+		} else {
+			rec.Problems = append(rec.Problems, Problem{Line: n, Kind: ProblemNoValue, Param: p.Name, Text: p.Name})
+		}
+		switch {
+		case p.Name == "":
+			rec.Problems = append(rec.Problems, Problem{Line: n, Kind: ProblemNoName, Text: strings.TrimSpace(field)})
+			continue
+		case seen[p.Name]:
+			rec.Problems = append(rec.Problems, Problem{Line: n, Kind: ProblemDuplicate, Param: p.Name, Text: p.Name + "=" + p.Value})
+		}
+		seen[p.Name] = true
+		rec.Params = append(rec.Params, p)
+	}
+	return rec
+}
+
+// splitParams splits the text after the colon on the commas outside quotes,
+// up to the terminating semicolon. Empty fields are dropped. It returns the
+// text after the semicolon and whether a quote was left open.
+func splitParams(s string) (fields []string, tail string, inQuote bool) {
+	start := 0
+	escaped := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inQuote && c == '\\':
+			escaped = true
+		case c == '"':
+			inQuote = !inQuote
+		case !inQuote && c == ',':
+			fields = appendField(fields, s[start:i])
+			start = i + 1
+		case !inQuote && c == ';':
+			return appendField(fields, s[start:i]), s[i+1:], false
+		}
+	}
+	return appendField(fields, s[start:]), "", inQuote
+}
+
+// appendField adds field unless it is empty, as after a trailing comma.
+func appendField(fields []string, field string) []string {
+	if strings.TrimSpace(field) == "" {
+		return fields
+	}
+	return append(fields, field)
+}
+
+// indexUnquoted returns the index of the first c outside quotes, or -1.
+func indexUnquoted(s string, c byte) int {
+	inQuote, escaped := false, false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case inQuote && s[i] == '\\':
+			escaped = true
+		case s[i] == '"':
+			inQuote = !inQuote
+		case !inQuote && s[i] == c:
+			return i
+		}
+	}
+	return -1
+}
+
+// Unquote returns a value without its double quotes and escapes, values
+// that are not quoted are returned as they are.
+func Unquote(v string) string {
+	if len(v) < 2 || v[0] != '"' || v[len(v)-1] != '"' {
+		return v
+	}
+	var b strings.Builder
+	escaped := false
+	for i := 1; i < len(v)-1; i++ {
+		if !escaped && v[i] == '\\' {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteByte(v[i])
+	}
+	return b.String()
+}
+
+// isName reports whether s may be a verb or MO: upper case letters, digits
+// and underscores, starting with a letter. Header keys like "NE Name" are not.
+func isName(s string) bool {
+	for i, c := range s {
+		switch {
+		case c >= 'A' && c <= 'Z':
+		case i > 0 && (c >= '0' && c <= '9' || c == '_'):
+		default:
+			return false
+		}
+	}
+	return s != ""
+}
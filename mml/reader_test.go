@@ -0,0 +1,177 @@
+package mml
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const script = `/*********************************************************************/
+/* MML Configuration Script                                          */
+/*********************************************************************/
+//NE Name: RNC_Magelang
+//NE Type: BSC6900 UMTS
+//NE Version: V900R019C10SPC500
+//Export Time: 2021-12-02 08:30:00
+//Export User: hw_sudi
+SET SYS:SYSOBJECTID="RNC1127";
+
+ADD UCELLSETUP:CELLID=11001,LAC=H'2B67,HSPAPLUSSWITCH=64QAM-1&MIMO-0;
+//ADD UCELLSETUP:CELLID=11003;
+no command here
+`
+
+func readAll(t *testing.T, r *Reader) []*Record {
+	t.Helper()
+
+	var recs []*Record
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return recs
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, rec)
+	}
+}
+
+func TestReader(t *testing.T) {
+	recs := readAll(t, NewReader(strings.NewReader(script)))
+
+	want := []*Record{
+		{Line: 9, Command: "SET", Object: "SYS", Params: []Param{{"SYSOBJECTID", `"RNC1127"`}}},
+		{Line: 11, Command: "ADD", Object: "UCELLSETUP", Params: []Param{
+			{"CELLID", "11001"},
+			{"LAC", "H'2B67"},
+			{"HSPAPLUSSWITCH", "64QAM-1&MIMO-0"},
+		}},
+		{Line: 12, Command: "ADD", Object: "UCELLSETUP", Params: []Param{{"CELLID", "11003"}}, Commented: true},
+	}
+	if !reflect.DeepEqual(recs, want) {
+		for _, r := range recs {
+			t.Logf("%+v", *r)
+		}
+		t.Fatalf("records differ from %d expected", len(want))
+	}
+	if got := recs[1].Name(); got != "ADD UCELLSETUP" {
+		t.Errorf("Name() = %q, want ADD UCELLSETUP", got)
+	}
+}
+
+func TestReaderHeader(t *testing.T) {
+	r := NewReader(strings.NewReader(script))
+	if _, err := r.Next(); err != nil {
+		t.Fatal(err)
+	}
+
+	h := r.Header()
+	if got := h.Get(KeyNEName); got != "RNC_Magelang" {
+		t.Errorf("NE Name %q, want RNC_Magelang", got)
+	}
+	if got := h.Get(KeyExportTime); got != "2021-12-02 08:30:00" {
+		t.Errorf("Export Time %q, want 2021-12-02 08:30:00", got)
+	}
+	if len(h.Keys) != 5 {
+		t.Errorf("header keys %q, want 5", h.Keys)
+	}
+
+	readAll(t, r)
+	if r.Lines() != 13 {
+		t.Errorf("read %d lines, want 13", r.Lines())
+	}
+}
+
+func TestReaderWithoutHeader(t *testing.T) {
+	r := NewReader(strings.NewReader("ADD UCELL:CELLID=1;\nMOD UCELL:CELLID=1;\n"))
+
+	recs := readAll(t, r)
+	if len(recs) != 2 || recs[0].Line != 1 || recs[1].Command != "MOD" {
+		t.Fatalf("got %d records, want ADD and MOD from line 1", len(recs))
+	}
+	if len(r.Header().Keys) != 0 {
+		t.Errorf("header %v, want none", r.Header().Fields)
+	}
+}
+
+func TestReaderQuotes(t *testing.T) {
+	r := NewReader(strings.NewReader(`ADD UCELLSETUP:CELLID=1,CELLNAME="A,B:C=D;E\"F\\",REMARK="x&y-1" ,LAC=H'2B67;MOD ignored:X=1` + "\n"))
+
+	recs := readAll(t, r)
+	if len(recs) != 1 {
+		t.Fatalf("got %d records, want 1", len(recs))
+	}
+	want := []Param{
+		{"CELLID", "1"},
+		{"CELLNAME", `"A,B:C=D;E\"F\\"`},
+		{"REMARK", `"x&y-1"`},
+		{"LAC", "H'2B67"},
+	}
+	if !reflect.DeepEqual(recs[0].Params, want) {
+		t.Fatalf("params %q, want %q", recs[0].Params, want)
+	}
+	if got := Unquote(recs[0].Params[1].Value); got != `A,B:C=D;E"F\` {
+		t.Errorf("Unquote = %q", got)
+	}
+}
+
+func TestReaderCommentMarker(t *testing.T) {
+	r := NewReader(strings.NewReader(`//ADD UEXT:URL="http://a//b",NOTE=x//y;` + "\n"))
+
+	recs := readAll(t, r)
+	if len(recs) != 1 || !recs[0].Commented {
+		t.Fatalf("records %+v, want one commented", recs)
+	}
+	want := []Param{{"URL", `"http://a//b"`}, {"NOTE", "x//y"}}
+	if !reflect.DeepEqual(recs[0].Params, want) {
+		t.Fatalf("params %q, want %q", recs[0].Params, want)
+	}
+}
+
+func TestReaderProblems(t *testing.T) {
+	r := NewReader(strings.NewReader(script + "ADD UCELL:CELLID=1,CELLID=2,PENDING,=5;extra\nADD UCELL:NAME=\"open;\n"))
+
+	recs := readAll(t, r)
+	if got := r.Problems(); !reflect.DeepEqual(got, []Problem{{Line: 13, Kind: ProblemNotCommand, Text: "no command here"}}) {
+		t.Errorf("skipped lines %+v", got)
+	}
+	if len(recs) != 5 {
+		t.Fatalf("got %d records, want 5", len(recs))
+	}
+	want := []Problem{
+		{Line: 14, Kind: ProblemTrailingText, Text: "extra"},
+		{Line: 14, Kind: ProblemDuplicate, Param: "CELLID", Text: "CELLID=2"},
+		{Line: 14, Kind: ProblemNoValue, Param: "PENDING", Text: "PENDING"},
+		{Line: 14, Kind: ProblemNoName, Text: "=5"},
+	}
+	if !reflect.DeepEqual(recs[3].Problems, want) {
+		t.Errorf("problems %+v, want %+v", recs[3].Problems, want)
+	}
+	if want := []Param{{"CELLID", "1"}, {"CELLID", "2"}, {"PENDING", ""}}; !reflect.DeepEqual(recs[3].Params, want) {
+		t.Errorf("params %q, want %q", recs[3].Params, want)
+	}
+	if len(recs[4].Problems) != 1 || recs[4].Problems[0].Kind != ProblemUnterminated {
+		t.Errorf("problems %+v, want an unterminated quote", recs[4].Problems)
+	}
+}
+
+func TestReaderBanner(t *testing.T) {
+	r := NewReader(strings.NewReader("+++    RNC_Magelang        2021-12-02 08:30:00\n" +
+		"MML Configuration Export\n" +
+		"//NE Name: RNC_Magelang\n" +
+		"ADD UCELL:CELLID=1;\n" +
+		"---    END\n"))
+
+	if recs := readAll(t, r); len(recs) != 1 {
+		t.Fatalf("got %d records, want 1", len(recs))
+	}
+	if got := r.Header().Get(KeyNEName); got != "RNC_Magelang" {
+		t.Errorf("NE Name %q, want RNC_Magelang", got)
+	}
+	// only what follows the first command is a problem
+	if got := r.Problems(); !reflect.DeepEqual(got, []Problem{{Line: 5, Kind: ProblemNotCommand, Text: "---    END"}}) {
+		t.Errorf("skipped lines %+v", got)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aksafarand/ftpdownloader/mml"
+)
+
+// Output formats of the parse command.
+const (
+	parseFormatJSONL = "jsonl"
+	parseFormatCSV   = "csv"
+)
+
+// parseRecord is one command as printed by the parse command in jsonl.
+type parseRecord struct {
+	NeName    string            `json:"ne"`
+	Source    string            `json:"source"`
+	Line      int               `json:"line"`
+	Verb      string            `json:"verb"`
+	MO        string            `json:"mo"`
+	Commented bool              `json:"commented"`
+	Params    map[string]string `json:"params"`
+
+	// order keeps the parameters as written for the csv columns
+	order []mml.Param
+}
+
+// runParse runs only the MML parser over one dump, a CFGMML script or an
+// archive of them, and prints its records to stdout, for checking one NE
+// without the FTP and Access pipeline:
+//
+//	hwTxtDumpMaker parse -in CFGMML-RNC1127-....txt -format jsonl -mo UCELL
+//
+// jsonl prints one object per command, csv a single table and needs -mo.
+func runParse(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("parse", flag.ContinueOnError)
+	flagIn := fs.String("in", "", "CFGMML Dump To Parse, Text or Archive")
+	flagFormat := fs.String("format", parseFormatJSONL, "Output Format: jsonl (One Record Per Line) or csv (One Table, Needs -mo)")
+	flagMO := fs.String("mo", "", "Only Commands Of These MOs, Comma Separated")
+	flagSkippedComment := fs.Bool("skip-comment", true, "Skipped // Lines")
+	flagCommentMode := fs.String("comment-mode", "", "Commented // Commands: skip, strip (Print As Active) or flag (Print As Commented), Overrides -skip-comment")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	format := strings.ToLower(*flagFormat)
+	commentMode := runOptions{SkipDoubleSlash: *flagSkippedComment, CommentMode: strings.ToLower(*flagCommentMode)}.commentMode()
+	mos := make(map[string]bool)
+	for _, mo := range strings.Split(*flagMO, ",") {
+		if mo = strings.ToUpper(strings.TrimSpace(mo)); mo != "" {
+			mos[mo] = true
+		}
+	}
+	switch {
+	case *flagIn == "":
+		return errors.New("no dump given, use -in")
+	case !validCommentMode(commentMode):
+		return fmt.Errorf("unknown comment mode %s, use skip, strip or flag", commentMode)
+	case format != parseFormatJSONL && format != parseFormatCSV:
+		return fmt.Errorf("unknown format %s, use jsonl or csv", format)
+	case format == parseFormatCSV && len(mos) != 1:
+		return errors.New("csv prints a single table, give one MO with -mo")
+	}
+
+	var records []parseRecord
+	err := walkArchive(*flagIn, defaultExtractLimits, nil, nil, func(member string, r io.Reader) error {
+		// a plain script is its own member
+		source := path.Join(filepath.Base(*flagIn), member)
+		if member == filepath.Base(*flagIn) {
+			source = member
+		}
+		mr := mml.NewReader(bufio.NewReader(r))
+		rec, err := mr.Next()
+		neName := neFromContent(mr.Header(), rec, nil)
+		for ; err == nil; rec, err = mr.Next() {
+			if rec.Commented && commentMode == commentSkip {
+				continue
+			}
+			commented := rec.Commented && commentMode == commentFlag
+			mo := rec.Object
+			if mo == "" {
+				mo = rec.Command
+			}
+			if len(mos) > 0 && !mos[mo] {
+				continue
+			}
+			pr := parseRecord{NeName: neName, Source: source, Line: rec.Line, Verb: rec.Command, MO: mo, Commented: commented, Params: make(map[string]string), order: rec.Params}
+			for _, p := range rec.Params {
+				pr.Params[p.Name] = mml.Unquote(p.Value)
+			}
+			if format == parseFormatJSONL {
+				line, err := json.Marshal(pr)
+				if err != nil {
+					return err
+				}
+				if _, err := fmt.Fprintf(stdout, "%s\n", line); err != nil {
+					return err
+				}
+				continue
+			}
+			records = append(records, pr)
+		}
+		if err != io.EOF {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if format == parseFormatCSV {
+		return writeParseTable(stdout, records)
+	}
+	return nil
+}
+
+// writeParseTable prints records as one CSV table, the parameter columns in
+// the order they first show up.
+func writeParseTable(stdout io.Writer, records []parseRecord) error {
+	header := []string{"NE NAME", "SOURCE FILE", "LINE", "VERB", isCommentedColumn}
+	cols := make(map[string]int)
+	for _, pr := range records {
+		for _, p := range pr.order {
+			if _, ok := cols[p.Name]; !ok {
+				cols[p.Name] = len(header)
+				header = append(header, p.Name)
+			}
+		}
+	}
+
+	w := csv.NewWriter(stdout)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	for _, pr := range records {
+		commented := "0"
+		if pr.Commented {
+			commented = "1"
+		}
+		row := make([]string, len(header))
+		copy(row, []string{pr.NeName, pr.Source, strconv.Itoa(pr.Line), pr.Verb, commented})
+		for _, p := range pr.order {
+			row[cols[p.Name]] = mml.Unquote(p.Value)
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
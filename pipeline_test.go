@@ -0,0 +1,1567 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+
+	"github.com/aksafarand/ftpdownloader/configs"
+	"github.com/aksafarand/ftpdownloader/ftptest"
+	"github.com/aksafarand/ftpdownloader/mml"
+)
+
+const testDate = "20211202"
+
+// testPipeline is a scratch working directory wired to a fake BAM.
+type testPipeline struct {
+	t   *testing.T
+	srv *ftptest.Server
+	dir string
+}
+
+func newTestPipeline(t *testing.T) *testPipeline {
+	t.Helper()
+
+	srv, err := ftptest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	if err := os.WriteFile("EMPTY.accdb", []byte("accdb"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	log.SetOutput(os.Stderr)
+
+	return &testPipeline{t: t, srv: srv, dir: dir}
+}
+
+// serveDump publishes fixture zipped as <member>.zip in folder.
+func (p *testPipeline) serveDump(folder, member, fixture string, mod time.Time) {
+	p.t.Helper()
+
+	p.srv.AddFile(folder+member+".zip", p.zipDump(member, fixture), mod)
+}
+
+func (p *testPipeline) zipDump(member, fixture string) []byte {
+	p.t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(testdataDir, fixture))
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create(member + ".txt")
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	w.Write(data)
+	if err := zw.Close(); err != nil {
+		p.t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func (p *testPipeline) writeConfigs(name string, cfgs []configs.Config) {
+	p.t.Helper()
+
+	for i := range cfgs {
+		cfgs[i].RemoteServer = p.srv.Addr()
+	}
+	data, err := json.Marshal(cfgs)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	if err := os.WriteFile(name, data, 0644); err != nil {
+		p.t.Fatal(err)
+	}
+}
+
+func (p *testPipeline) run(opts runOptions) {
+	p.t.Helper()
+
+	opts.CSVOnly = true
+	dataProcess("3G", testDate, make(chan string), opts)
+}
+
+func (p *testPipeline) readCSV(elem ...string) [][]string {
+	p.t.Helper()
+
+	f, err := os.Open(filepath.Join(append([]string{p.dir, "result", testDate, "3G"}, elem...)...))
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	return rows
+}
+
+// column returns the values of col in rows, keyed by NE NAME.
+func column(t *testing.T, rows [][]string, col string) map[string][]string {
+	t.Helper()
+
+	idx := -1
+	for i, h := range rows[0] {
+		if h == col {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		t.Fatalf("column %q not in header %v", col, rows[0])
+	}
+	values := make(map[string][]string)
+	for _, r := range rows[1:] {
+		if idx < len(r) {
+			values[r[0]] = append(values[r[0]], r[idx])
+		}
+	}
+	return values
+}
+
+// testdataDir is resolved before any test changes the working directory.
+var testdataDir, _ = filepath.Abs("testdata")
+
+var testConfigs = []configs.Config{
+	{
+		FtpName:      "Huawei_Magelang",
+		RemoteFolder: configs.Folders{"/bam/version_a/ftp/export_cfgmml/"},
+		FilePrefix:   "CFGMML-RNC1127-",
+		Region:       "Central Java",
+		Part:         "1",
+	},
+	{
+		FtpName:      "Huawei_Kudus",
+		RemoteFolder: configs.Folders{"/bam/version_b/ftp/export_cfgmml/"},
+		FilePrefix:   "CFGMML-RNC1198-",
+		Region:       "Central Java",
+		Part:         "2",
+	},
+}
+
+func TestPipelineDownloadAndParse(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+	p.serveDump("/bam/version_a/ftp/export_cfgmml/", "CFGMML-RNC1127-127.0.0.1-20211202083000", "CFGMML-RNC1127.txt", mod)
+	p.serveDump("/bam/version_b/ftp/export_cfgmml/", "CFGMML-RNC1198-127.0.0.1-20211202074512", "CFGMML-RNC1198.txt", mod)
+	// an older export and another NE in the same folder must be ignored
+	p.serveDump("/bam/version_a/ftp/export_cfgmml/", "CFGMML-RNC1127-127.0.0.1-20211201083000", "CFGMML-RNC1198.txt", mod.AddDate(0, 0, -1))
+	p.writeConfigs("listrnc3g.json", append([]configs.Config(nil), testConfigs...))
+
+	p.run(runOptions{SkipDoubleSlash: true})
+
+	manifest := p.readCSV("manifest.csv")
+	if len(manifest) != 3 {
+		t.Fatalf("manifest has %d rows, want 3", len(manifest))
+	}
+	// dumps are parsed straight from the archives
+	if _, err := os.Stat(filepath.Join(p.dir, "result", testDate, "3G", "Central Java", stagingDir)); !os.IsNotExist(err) {
+		t.Errorf("dumps extracted to disk without -keep-csv")
+	}
+	for _, r := range manifest[1:] {
+		if r[3] != statusDownloaded || r[5] != testDate {
+			t.Errorf("manifest row %v, want %s on %s", r, statusDownloaded, testDate)
+		}
+	}
+
+	for _, folder := range []string{"Central Java", "National"} {
+		rows := p.readCSV(folder, "_dumpresult", "UCELLSETUP.csv")
+		cells := column(t, rows, "CELLID")
+		if got := strings.Join(cells["Huawei_Magelang"], ","); got != "11001,11002" {
+			t.Errorf("%s: Huawei_Magelang cells %q, want 11001,11002", folder, got)
+		}
+		if got := strings.Join(cells["Huawei_Kudus"], ","); got != "21001" {
+			t.Errorf("%s: Huawei_Kudus cells %q, want 21001", folder, got)
+		}
+		if got := column(t, rows, "LAC")["Huawei_Magelang"][0]; got != "11111" {
+			t.Errorf("%s: LAC %q, want 11111", folder, got)
+		}
+		if got := column(t, rows, "HSPAPLUSSWITCH_MIMO")["Huawei_Magelang"][1]; got != "1" {
+			t.Errorf("%s: HSPAPLUSSWITCH_MIMO %q, want 1", folder, got)
+		}
+		if got := column(t, rows, "DUMP DATE")["Huawei_Kudus"][0]; got != testDate {
+			t.Errorf("%s: DUMP DATE %q, want %s", folder, got, testDate)
+		}
+	}
+
+	info := p.readCSV("Central Java", "_dumpresult", dumpInfoTable+".csv")
+	for col, want := range map[string]string{
+		"HEADER NE NAME": "RNC_Magelang",
+		"NE VERSION":     "V900R019C10SPC500",
+		"EXPORT TIME":    "2021-12-02 08:30:00",
+		"SOURCE FILE":    "Huawei_Magelang_20211202.zip/CFGMML-RNC1127-127.0.0.1-20211202083000.txt",
+		"LINE COUNT":     "13",
+	} {
+		if got := column(t, info, col)["Huawei_Magelang"]; len(got) != 1 || got[0] != want {
+			t.Errorf("%s: %s %q, want %q", dumpInfoTable, col, got, want)
+		}
+	}
+}
+
+func TestPipelineFileRules(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+	// Magelang exports in two parts plus a readme, an older part is ignored
+	folderA := "/bam/version_a/ftp/export_cfgmml/"
+	for name, data := range map[string][]byte{
+		"CFGMML-RNC1127-127.0.0.1-20211202083000_1.zip": zipMembers(t, [2]string{"part1.txt", "ADD UCELLSETUP:CELLID=11001;\n"}),
+		"CFGMML-RNC1127-127.0.0.1-20211202083000_2.zip": zipMembers(t, [2]string{"part2.txt", "ADD UCELLSETUP:CELLID=11002;\n"}),
+		"CFGMML-RNC1127-127.0.0.1-20211201083000_1.zip": zipMembers(t, [2]string{"part1.txt", "ADD UCELLSETUP:CELLID=19999;\n"}),
+		"README-RNC1127-20211202083000.zip":             zipMembers(t, [2]string{"readme.txt", "export of RNC1127\n"}),
+	} {
+		p.srv.AddFile(folderA+name, data, mod)
+	}
+	// Kudus lacks the companion file it requires
+	p.serveDump("/bam/version_b/ftp/export_cfgmml/", "CFGMML-RNC1198-127.0.0.1-20211202074512", "CFGMML-RNC1198.txt", mod)
+
+	cfgs := append([]configs.Config(nil), testConfigs...)
+	cfgs[0].Files = []configs.FileRule{
+		{FilePattern: "CFGMML-RNC1127-*.zip", Select: configs.SelectAll},
+		{FilePrefix: "README-RNC1127-", LocalName: "readme"},
+		{FilePrefix: "NEINFO-RNC1127-", LocalName: "neinfo", Optional: true},
+	}
+	cfgs[1].Files = []configs.FileRule{
+		{FilePrefix: "CFGMML-RNC1198-"},
+		{FilePrefix: "NEINFO-RNC1198-", LocalName: "neinfo"},
+	}
+	p.writeConfigs("listrnc3g.json", cfgs)
+
+	p.run(runOptions{SkipDoubleSlash: true})
+
+	status := make(map[string][]string)
+	for _, r := range p.readCSV("manifest.csv")[1:] {
+		status[r[0]] = r
+	}
+	if r := status["Huawei_Magelang"]; r[3] != statusDownloaded || r[6] != folderA+"CFGMML-RNC1127-127.0.0.1-20211202083000_1.zip | "+folderA+"CFGMML-RNC1127-127.0.0.1-20211202083000_2.zip | "+folderA+"README-RNC1127-20211202083000.zip" {
+		t.Errorf("Huawei_Magelang manifest row %v, want both parts and the readme", r)
+	}
+	if r := status["Huawei_Kudus"]; r[3] != statusFailed {
+		t.Errorf("Huawei_Kudus manifest row %v, want %s without its required companion", r, statusFailed)
+	}
+
+	for _, folder := range []string{"Central Java", "National"} {
+		var saved []string
+		files, err := filepath.Glob(filepath.Join(p.dir, "result", testDate, "3G", folder, "Huawei_*.zip"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range files {
+			saved = append(saved, filepath.Base(f))
+		}
+		// the part Kudus had already fetched is removed again
+		if got := strings.Join(saved, ","); got != "Huawei_Magelang_20211202_1.zip,Huawei_Magelang_20211202_2.zip,Huawei_Magelang_20211202_readme.zip" {
+			t.Errorf("%s: saved %s", folder, got)
+		}
+	}
+
+	cells := column(t, p.readCSV("Central Java", "_dumpresult", "UCELLSETUP.csv"), "CELLID")
+	if got := strings.Join(cells["Huawei_Magelang"], ","); got != "11001,11002" {
+		t.Errorf("Huawei_Magelang cells %q, want 11001,11002 from both parts", got)
+	}
+	if got := cells["Huawei_Kudus"]; len(got) != 0 {
+		t.Errorf("Huawei_Kudus cells %q, want none", got)
+	}
+}
+
+func TestPipelineFailures(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+	p.serveDump("/bam/version_a/ftp/export_cfgmml/", "CFGMML-RNC1127-127.0.0.1-20211202083000", "CFGMML-RNC1127.txt", mod)
+	p.serveDump("/bam/version_b/ftp/export_cfgmml/", "CFGMML-RNC1198-127.0.0.1-20211202074512", "CFGMML-RNC1198.txt", mod)
+	p.srv.Fail("RETR /bam/version_b/ftp/export_cfgmml/CFGMML-RNC1198-127.0.0.1-20211202074512.zip", "550 Permission denied")
+	p.srv.Delay("LIST", 50*time.Millisecond)
+
+	cfgs := append([]configs.Config(nil), testConfigs...)
+	cfgs = append(cfgs, configs.Config{
+		FtpName:      "Huawei_Medan",
+		RemoteFolder: configs.Folders{"/bam/version_c/ftp/export_cfgmml/"},
+		FilePrefix:   "CFGMML-RNC1201-",
+		Region:       "North Sumatra",
+		Part:         "2",
+	})
+	p.writeConfigs("listrnc3g.json", cfgs)
+
+	p.run(runOptions{SkipDoubleSlash: true})
+
+	status := make(map[string]string)
+	for _, r := range p.readCSV("manifest.csv")[1:] {
+		status[r[0]] = r[3]
+	}
+	want := map[string]string{
+		"Huawei_Magelang": statusDownloaded,
+		"Huawei_Kudus":    statusFailed,
+		"Huawei_Medan":    statusFailed,
+	}
+	for ne, s := range want {
+		if status[ne] != s {
+			t.Errorf("%s: status %q, want %q", ne, status[ne], s)
+		}
+	}
+
+	cells := column(t, p.readCSV("National", "_dumpresult", "UCELLSETUP.csv"), "CELLID")
+	if len(cells) != 1 || len(cells["Huawei_Magelang"]) != 2 {
+		t.Errorf("national cells %v, want only Huawei_Magelang", cells)
+	}
+}
+
+func TestPipelineFallback(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+	p.serveDump("/bam/version_a/ftp/export_cfgmml/", "CFGMML-RNC1127-127.0.0.1-20211202083000", "CFGMML-RNC1127.txt", mod)
+	// today's export of Kudus is missing, the one from two days ago is still there
+	p.serveDump("/bam/version_b/ftp/export_cfgmml/", "CFGMML-RNC1198-127.0.0.1-20211130074512", "CFGMML-RNC1198.txt", mod.AddDate(0, 0, -2))
+	p.writeConfigs("listrnc3g.json", append([]configs.Config(nil), testConfigs...))
+
+	p.run(runOptions{SkipDoubleSlash: true, FallbackDays: 3})
+
+	for _, r := range p.readCSV("manifest.csv")[1:] {
+		if r[0] == "Huawei_Kudus" && (r[3] != statusFallback || r[4] != "true" || r[5] != "20211130") {
+			t.Errorf("manifest row %v, want a substituted dump of 20211130", r)
+		}
+	}
+
+	dates := column(t, p.readCSV("National", "_dumpresult", "UCELLSETUP.csv"), "DUMP DATE")
+	if dates["Huawei_Kudus"][0] != "20211130" || dates["Huawei_Magelang"][0] != testDate {
+		t.Errorf("DUMP DATE %v, want 20211130 for Huawei_Kudus only", dates)
+	}
+
+	// Magelang exports plain text and has no export at all today, only the
+	// dump we kept from yesterday, next to its Access output
+	p = newTestPipeline(t)
+	p.serveDump("/bam/version_b/ftp/export_cfgmml/", "CFGMML-RNC1198-127.0.0.1-20211202074512", "CFGMML-RNC1198.txt", mod)
+	p.writeConfigs("listrnc3g.json", append([]configs.Config(nil), testConfigs...))
+	kept := filepath.Join("result", "20211201", "3G", "Central Java")
+	if err := os.MkdirAll(kept, 0755); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(testdataDir, "CFGMML-RNC1127.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string][]byte{"Huawei_Magelang_20211201.txt": data, "Huawei_Magelang_20211201.accdb": []byte("accdb")} {
+		if err := os.WriteFile(filepath.Join(kept, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p.run(runOptions{SkipDoubleSlash: true, FallbackDays: 3})
+
+	for _, r := range p.readCSV("manifest.csv")[1:] {
+		if r[0] == "Huawei_Magelang" && (r[3] != statusFallback || r[5] != "20211201") {
+			t.Errorf("manifest row %v, want the local text dump of 20211201", r)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(p.dir, "result", testDate, "3G", "Central Java", "Huawei_Magelang_"+testDate+".accdb")); err == nil {
+		t.Error("Access output of 20211201 copied as a dump")
+	}
+	dates = column(t, p.readCSV("National", "_dumpresult", "UCELLSETUP.csv"), "DUMP DATE")
+	if len(dates["Huawei_Magelang"]) == 0 || dates["Huawei_Magelang"][0] != "20211201" {
+		t.Errorf("DUMP DATE %v, want 20211201 for Huawei_Magelang", dates)
+	}
+}
+
+func TestPipelineFolderDiscovery(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+	// Magelang flipped to version_b after an upgrade, version_a still holds a stale export
+	p.serveDump("/bam/version_a/ftp/export_cfgmml/", "CFGMML-RNC1127-127.0.0.1-20211202013000", "CFGMML-RNC1198.txt", mod.Add(-7*time.Hour))
+	p.serveDump("/bam/version_b/ftp/export_cfgmml/", "CFGMML-RNC1127-127.0.0.1-20211202083000", "CFGMML-RNC1127.txt", mod)
+	p.serveDump("/bam/version_b/ftp/export_cfgmml/", "CFGMML-RNC1198-127.0.0.1-20211202074512", "CFGMML-RNC1198.txt", mod)
+
+	cfgs := append([]configs.Config(nil), testConfigs...)
+	cfgs[0].RemoteFolder = configs.Folders{"/bam/version_*/ftp/export_cfgmml/"}
+	cfgs[1].RemoteFolder = configs.Folders{"/bam/version_c/ftp/export_cfgmml/", "/bam/version_b/ftp/export_cfgmml/"}
+	p.writeConfigs("listrnc3g.json", cfgs)
+
+	p.run(runOptions{SkipDoubleSlash: true})
+
+	folder := make(map[string]string)
+	for _, r := range p.readCSV("manifest.csv")[1:] {
+		if r[3] != statusDownloaded {
+			t.Errorf("manifest row %v, want %s", r, statusDownloaded)
+		}
+		folder[r[0]] = r[7]
+	}
+	for _, ne := range []string{"Huawei_Magelang", "Huawei_Kudus"} {
+		if folder[ne] != "/bam/version_b/ftp/export_cfgmml" {
+			t.Errorf("%s: folder %q, want /bam/version_b/ftp/export_cfgmml", ne, folder[ne])
+		}
+	}
+
+	cells := column(t, p.readCSV("Central Java", "_dumpresult", "UCELLSETUP.csv"), "CELLID")
+	if got := strings.Join(cells["Huawei_Magelang"], ","); got != "11001,11002" {
+		t.Errorf("Huawei_Magelang cells %q, want 11001,11002 from version_b", got)
+	}
+}
+
+func TestParseListLine(t *testing.T) {
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		line string
+		ok   bool
+		want remoteFile
+	}{
+		// DOS, as served by the BAM
+		{"12-02-21  08:30AM             37192705 CFGMML-RNC1127-10.7.245.18-20211202083000.zip\r\n", true, remoteFile{Name: "CFGMML-RNC1127-10.7.245.18-20211202083000.zip", Size: 37192705, ModTime: mod}},
+		{"12-02-21  08:30PM       <DIR>          export_cfgmml", true, remoteFile{Name: "export_cfgmml", ModTime: mod.Add(12 * time.Hour), IsDir: true}},
+		{"12-02-21  08:30AM                  100 CFGMML RNC1127 copy.zip", true, remoteFile{Name: "CFGMML RNC1127 copy.zip", Size: 100, ModTime: mod}},
+
+		// Unix ls -l with a year, and a directory
+		{"-rw-r--r--   1 ftp      ftp      37192705 Dec 02  2021 CFGMML-RNC1127-10.7.245.18-20211202083000.zip", true, remoteFile{Name: "CFGMML-RNC1127-10.7.245.18-20211202083000.zip", Size: 37192705, ModTime: time.Date(2021, 12, 2, 0, 0, 0, 0, time.UTC)}},
+		{"drwxr-xr-x   2 ftp      ftp          4096 Dec 02  2021 export cfgmml", true, remoteFile{Name: "export cfgmml", Size: 4096, ModTime: time.Date(2021, 12, 2, 0, 0, 0, 0, time.UTC), IsDir: true}},
+
+		// MLSD facts
+		{"type=file;size=37192705;modify=20211202083000; CFGMML-RNC1127-10.7.245.18-20211202083000.zip", true, remoteFile{Name: "CFGMML-RNC1127-10.7.245.18-20211202083000.zip", Size: 37192705, ModTime: mod}},
+		{"type=file;size=100;modify=20211202083000.123; CFGMML RNC1127.zip", true, remoteFile{Name: "CFGMML RNC1127.zip", Size: 100, ModTime: mod}},
+		{"Type=dir;Modify=20211202083000; export_cfgmml", true, remoteFile{Name: "export_cfgmml", ModTime: mod, IsDir: true}},
+		{"type=cdir;modify=20211202083000; .", true, remoteFile{Name: ".", ModTime: mod, IsDir: true}},
+
+		{"", false, remoteFile{}},
+		{"total 12", false, remoteFile{}},
+	} {
+		got, ok := parseListLine(tc.line)
+		if ok != tc.ok || got.Name != tc.want.Name || got.Size != tc.want.Size || !got.ModTime.Equal(tc.want.ModTime) || got.IsDir != tc.want.IsDir {
+			t.Errorf("%q: %+v %v, want %+v %v", tc.line, got, ok, tc.want, tc.ok)
+		}
+	}
+
+	// without a year the time is within the last six months
+	now := time.Now()
+	recent := now.AddDate(0, -1, 0)
+	got, ok := parseListLine("-rw-r--r--   1 ftp      ftp      100 " + recent.Format("Jan 02 15:04") + " CFGMML-RNC1127.zip")
+	if !ok || got.ModTime.Year() != recent.Year() || got.ModTime.Month() != recent.Month() || got.ModTime.Day() != recent.Day() || got.ModTime.Hour() != recent.Hour() {
+		t.Errorf("recent listing %+v %v, want %s", got, ok, recent)
+	}
+	future := now.AddDate(0, 2, 0)
+	got, _ = parseListLine("-rw-r--r--   1 ftp      ftp      100 " + future.Format("Jan 02 15:04") + " CFGMML-RNC1127.zip")
+	if got.ModTime.Year() != future.Year()-1 {
+		t.Errorf("listing dated after today %+v, want last year's", got)
+	}
+}
+
+func TestPickRemoteFile(t *testing.T) {
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+	candidate := func(name, date, tm string, modTime time.Time) remoteCandidate {
+		return remoteCandidate{remoteFile: remoteFile{Name: name, ModTime: modTime}, folder: "/bam", match: configs.FileMatch{Name: name, Date: date, Time: tm}}
+	}
+	for _, tc := range []struct {
+		candidates []remoteCandidate
+		name       string
+		reason     string
+	}{
+		{[]remoteCandidate{candidate("a.zip", "", "", mod)}, "a.zip", "Single Match"},
+		// the time in the name wins over a later modification
+		{[]remoteCandidate{
+			candidate("late.zip", "20211202", "083000", mod),
+			candidate("early.zip", "20211202", "013000", mod.Add(time.Hour)),
+		}, "late.zip", "Newest Date/Time In File Name"},
+		{[]remoteCandidate{
+			candidate("old.zip", "", "", mod),
+			candidate("new.zip", "", "", mod.Add(time.Minute)),
+		}, "new.zip", "Newest Modification Time"},
+		{[]remoteCandidate{
+			candidate("b.zip", "20211202", "083000", mod),
+			candidate("c.zip", "20211202", "083000", mod),
+			candidate("a.zip", "20211202", "083000", mod),
+		}, "c.zip", "Last File Name"},
+	} {
+		best, reason := pickRemoteFile(tc.candidates)
+		if best.Name != tc.name || reason != tc.reason {
+			t.Errorf("picked %s by %s, want %s by %s", best.Name, reason, tc.name, tc.reason)
+		}
+	}
+
+	// the pick is logged with its reason and every candidate
+	hook := new(logtest.Hook)
+	hooks := log.StandardLogger().ReplaceHooks(log.LevelHooks{})
+	defer log.StandardLogger().ReplaceHooks(hooks)
+	log.AddHook(hook)
+
+	cfg := configs.Config{FtpName: "Huawei_Magelang", RemoteServer: "127.0.0.1:21", RemoteFolder: configs.Folders{"/bam/"}, FilePattern: `re:CFGMML-RNC1127-(?P<ip>[0-9.]+)-(?P<date>\d{8})(?P<time>\d{6})\.zip`}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	listings := []remoteListing{{Folder: "/bam", Lines: []string{
+		"12-02-21  08:40AM                  100 CFGMML-RNC1127-127.0.0.1-20211202013000.zip",
+		"12-02-21  08:30AM                  100 CFGMML-RNC1127-127.0.0.1-20211202083000.zip",
+		"12-02-21  08:50AM                  100 CFGMML-RNC11270-127.0.0.1-20211202093000.zip",
+	}}}
+	picked := findRemoteFiles(listings, cfg, cfg.Files[0], "202112020")
+	if len(picked) != 1 || picked[0].Name != "CFGMML-RNC1127-127.0.0.1-20211202083000.zip" {
+		t.Fatalf("picked %+v", picked)
+	}
+	want := "Selected: /bam/CFGMML-RNC1127-127.0.0.1-20211202083000.zip From: Huawei_Magelang Out Of 2 Candidates By Newest Date/Time In File Name: " +
+		"/bam/CFGMML-RNC1127-127.0.0.1-20211202013000.zip, /bam/CFGMML-RNC1127-127.0.0.1-20211202083000.zip"
+	if e := hook.LastEntry(); e == nil || e.Message != want {
+		t.Errorf("logged %+v, want %s", e, want)
+	}
+}
+
+func TestPipelineWaitsForStableFile(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+	p.serveDump("/bam/version_b/ftp/export_cfgmml/", "CFGMML-RNC1198-127.0.0.1-20211202074512", "CFGMML-RNC1198.txt", mod)
+
+	// the export of Magelang is still being written for the first two listings
+	folder := "/bam/version_a/ftp/export_cfgmml/"
+	name := "CFGMML-RNC1127-127.0.0.1-20211202083000"
+	full := p.zipDump(name, "CFGMML-RNC1127.txt")
+	p.srv.AddFile(folder+name+".zip", full[:len(full)/3], mod)
+	lists := 0
+	p.srv.OnCommand("LIST "+folder, func() {
+		lists++
+		switch lists {
+		case 2:
+			p.srv.AddFile(folder+name+".zip", full[:2*len(full)/3], mod)
+		case 3:
+			p.srv.AddFile(folder+name+".zip", full, mod.Add(time.Minute))
+		}
+	})
+	p.writeConfigs("listrnc3g.json", append([]configs.Config(nil), testConfigs...))
+
+	p.run(runOptions{SkipDoubleSlash: true, StableInterval: 10 * time.Millisecond, StableMaxWait: time.Second})
+
+	if lists != 4 {
+		t.Errorf("listed %d times, want 4", lists)
+	}
+	cells := column(t, p.readCSV("National", "_dumpresult", "UCELLSETUP.csv"), "CELLID")
+	if got := strings.Join(cells["Huawei_Magelang"], ","); got != "11001,11002" {
+		t.Errorf("Huawei_Magelang cells %q, want 11001,11002 from the complete export", got)
+	}
+}
+
+func TestPipelineGivesUpOnChangingFile(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+	p.serveDump("/bam/version_b/ftp/export_cfgmml/", "CFGMML-RNC1198-127.0.0.1-20211202074512", "CFGMML-RNC1198.txt", mod)
+
+	folder := "/bam/version_a/ftp/export_cfgmml/"
+	name := "CFGMML-RNC1127-127.0.0.1-20211202083000.zip"
+	size := 1
+	p.srv.AddFile(folder+name, make([]byte, size), mod)
+	p.srv.OnCommand("LIST "+folder, func() {
+		size++
+		p.srv.AddFile(folder+name, make([]byte, size), mod)
+	})
+	p.writeConfigs("listrnc3g.json", append([]configs.Config(nil), testConfigs...))
+
+	p.run(runOptions{SkipDoubleSlash: true, StableInterval: 10 * time.Millisecond, StableMaxWait: 100 * time.Millisecond})
+
+	for _, r := range p.readCSV("manifest.csv")[1:] {
+		if r[0] == "Huawei_Magelang" && r[3] != statusFailed {
+			t.Errorf("manifest row %v, want %s", r, statusFailed)
+		}
+	}
+	for _, c := range p.srv.Commands() {
+		if c == "RETR "+folder+name {
+			t.Errorf("changing file was downloaded")
+		}
+	}
+}
+
+func TestPipelineArchiveFormats(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+
+	magelang, err := os.ReadFile(filepath.Join(testdataDir, "CFGMML-RNC1127.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: "CFGMML-RNC1127-127.0.0.1-20211202083000.txt", Mode: 0644, Size: int64(len(magelang)), Typeflag: tar.TypeReg})
+	tw.Write(magelang)
+	tw.Close()
+	gz.Close()
+	p.srv.AddFile("/bam/version_a/ftp/export_cfgmml/CFGMML-RNC1127-127.0.0.1-20211202083000.tar.gz", buf.Bytes(), mod)
+
+	// a plain dump behind a misleading extension
+	kudus, err := os.ReadFile(filepath.Join(testdataDir, "CFGMML-RNC1198.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.srv.AddFile("/bam/version_b/ftp/export_cfgmml/CFGMML-RNC1198-127.0.0.1-20211202074512.zip", kudus, mod)
+	p.writeConfigs("listrnc3g.json", append([]configs.Config(nil), testConfigs...))
+
+	p.run(runOptions{SkipDoubleSlash: true})
+
+	cells := column(t, p.readCSV("National", "_dumpresult", "UCELLSETUP.csv"), "CELLID")
+	if got := strings.Join(cells["Huawei_Magelang"], ","); got != "11001,11002" {
+		t.Errorf("Huawei_Magelang cells %q, want 11001,11002 from the tar.gz", got)
+	}
+	if got := strings.Join(cells["Huawei_Kudus"], ","); got != "21001" {
+		t.Errorf("Huawei_Kudus cells %q, want 21001 from the plain dump", got)
+	}
+}
+
+// zipMembers zips data under the given member names, in order.
+func zipMembers(t *testing.T, members ...[2]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, m := range members {
+		w, err := zw.Create(m[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(m[1]))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestPipelineKeepsParsedDumps(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+
+	// with -keep-csv the parsed dumps are kept per NE, same member names included
+	for _, ne := range []struct{ folder, prefix, fixture string }{
+		{"/bam/version_a/ftp/export_cfgmml/", "CFGMML-RNC1127-", "CFGMML-RNC1127.txt"},
+		{"/bam/version_b/ftp/export_cfgmml/", "CFGMML-RNC1198-", "CFGMML-RNC1198.txt"},
+	} {
+		data, err := os.ReadFile(filepath.Join(testdataDir, ne.fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.srv.AddFile(ne.folder+ne.prefix+"127.0.0.1-20211202083000.zip", zipMembers(t, [2]string{"export/CFGMML.txt", string(data)}), mod)
+	}
+	p.writeConfigs("listrnc3g.json", append([]configs.Config(nil), testConfigs...))
+
+	p.run(runOptions{SkipDoubleSlash: true, KeepCSV: true})
+
+	for _, ne := range []string{"Huawei_Magelang", "Huawei_Kudus"} {
+		staged := filepath.Join(p.dir, "result", testDate, "3G", "Central Java", stagingDir, ne, ne+"_"+testDate, "export", "CFGMML.txt")
+		if _, err := os.Stat(staged); err != nil {
+			t.Errorf("%s not staged: %s", ne, err)
+		}
+	}
+	cells := column(t, p.readCSV("Central Java", "_dumpresult", "UCELLSETUP.csv"), "CELLID")
+	if got := strings.Join(cells["Huawei_Magelang"], ","); got != "11001,11002" {
+		t.Errorf("Huawei_Magelang cells %q, want 11001,11002", got)
+	}
+	if got := strings.Join(cells["Huawei_Kudus"], ","); got != "21001" {
+		t.Errorf("Huawei_Kudus cells %q, want 21001", got)
+	}
+}
+
+func TestPipelineSameMemberNames(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+
+	// both NEs export the same member name, neither may overwrite the other
+	for _, ne := range []struct{ folder, prefix, fixture string }{
+		{"/bam/version_a/ftp/export_cfgmml/", "CFGMML-RNC1127-", "CFGMML-RNC1127.txt"},
+		{"/bam/version_b/ftp/export_cfgmml/", "CFGMML-RNC1198-", "CFGMML-RNC1198.txt"},
+	} {
+		data, err := os.ReadFile(filepath.Join(testdataDir, ne.fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.srv.AddFile(ne.folder+ne.prefix+"127.0.0.1-20211202083000.zip", zipMembers(t, [2]string{"CFGMML.txt", string(data)}), mod)
+	}
+	p.writeConfigs("listrnc3g.json", append([]configs.Config(nil), testConfigs...))
+
+	p.run(runOptions{SkipDoubleSlash: true})
+
+	rows := p.readCSV("Central Java", "_dumpresult", "UCELLSETUP.csv")
+	for col, want := range map[string]map[string]string{
+		"CELLID":   {"Huawei_Magelang": "11001,11002", "Huawei_Kudus": "21001"},
+		"CELLNAME": {"Huawei_Magelang": "MGL001_1,MGL001_2", "Huawei_Kudus": "KDS001_1"},
+		"LAC":      {"Huawei_Magelang": "11111,11111", "Huawei_Kudus": "11265"},
+	} {
+		got := column(t, rows, col)
+		for ne, w := range want {
+			if g := strings.Join(got[ne], ","); g != w {
+				t.Errorf("%s %s %q, want %q", ne, col, g, w)
+			}
+		}
+	}
+	for _, r := range rows[1:] {
+		if len(r) != len(rows[0]) {
+			t.Errorf("%s: row of %d columns, header has %d", r[0], len(r), len(rows[0]))
+		}
+	}
+}
+
+func TestPipelineUnsafeArchives(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+
+	p.srv.AddFile("/bam/version_a/ftp/export_cfgmml/CFGMML-RNC1127-127.0.0.1-20211202083000.zip", zipMembers(t, [2]string{"../../../../evil.txt", "x"}), mod)
+	// compresses far better than the allowed ratio
+	p.srv.AddFile("/bam/version_b/ftp/export_cfgmml/CFGMML-RNC1198-127.0.0.1-20211202074512.zip", zipMembers(t, [2]string{"bomb.txt", strings.Repeat("A", 1<<20)}), mod)
+	p.writeConfigs("listrnc3g.json", append([]configs.Config(nil), testConfigs...))
+
+	p.run(runOptions{SkipDoubleSlash: true, ExtractLimits: extractLimits{MaxRatio: 50}})
+
+	for _, evil := range []string{"evil.txt", filepath.Join("result", "evil.txt"), filepath.Join("result", testDate, "evil.txt")} {
+		if _, err := os.Stat(filepath.Join(p.dir, evil)); err == nil {
+			t.Errorf("member escaped to %s", evil)
+		}
+	}
+
+	manifest := p.readCSV("manifest.csv")
+	errs := make(map[string]string)
+	for _, r := range manifest[1:] {
+		errs[r[0]] = r[8]
+	}
+	if !strings.Contains(errs["Huawei_Magelang"], "outside destination") {
+		t.Errorf("Huawei_Magelang extract error %q, want path traversal", errs["Huawei_Magelang"])
+	}
+	if !strings.Contains(errs["Huawei_Kudus"], "exceeds limit") {
+		t.Errorf("Huawei_Kudus extract error %q, want size limit", errs["Huawei_Kudus"])
+	}
+}
+
+func TestPipelineNestedArchives(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+
+	magelang, err := os.ReadFile(filepath.Join(testdataDir, "CFGMML-RNC1127.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kudus, err := os.ReadFile(filepath.Join(testdataDir, "CFGMML-RNC1198.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the dump sits in a zip inside the zip, next to files that are no dump
+	inner := zipMembers(t, [2]string{"CFGMML-RNC1127.txt", string(magelang)})
+	p.srv.AddFile("/bam/version_a/ftp/export_cfgmml/CFGMML-RNC1127-127.0.0.1-20211202083000.zip", zipMembers(t,
+		[2]string{"readme.txt", "Exported by BAM.\nNOTE: keep for 7 days\n"},
+		[2]string{"export.log", "2021-12-02 08:30:00 export done\n"},
+		[2]string{"dump.zip", string(inner)},
+	), mod)
+	// an old dump of another NE is left in the archive and excluded by config
+	p.srv.AddFile("/bam/version_b/ftp/export_cfgmml/CFGMML-RNC1198-127.0.0.1-20211202074512.zip", zipMembers(t,
+		[2]string{"CFGMML-RNC1198.txt", string(kudus)},
+		[2]string{"old/CFGMML-RNC1127.txt", string(magelang)},
+	), mod)
+
+	cfgs := append([]configs.Config(nil), testConfigs...)
+	cfgs[1].Exclude = []string{"old/*"}
+	p.writeConfigs("listrnc3g.json", cfgs)
+
+	p.run(runOptions{SkipDoubleSlash: true, ExtractLimits: extractLimits{MaxDepth: 1}})
+
+	rows := p.readCSV("Central Java", "_dumpresult", "UCELLSETUP.csv")
+	cells := column(t, rows, "CELLID")
+	if got := strings.Join(cells["Huawei_Magelang"], ","); got != "11001,11002" {
+		t.Errorf("Huawei_Magelang cells %q, want 11001,11002 from the nested zip", got)
+	}
+	if got := strings.Join(cells["Huawei_Kudus"], ","); got != "21001" {
+		t.Errorf("Huawei_Kudus cells %q, want 21001 without the excluded member", got)
+	}
+	for _, r := range p.readCSV("manifest.csv")[1:] {
+		if r[8] != "" {
+			t.Errorf("%s: extract error %q", r[0], r[8])
+		}
+	}
+
+	// without nesting the dump inside dump.zip is never reached
+	p.run(runOptions{SkipDoubleSlash: true})
+	cells = column(t, p.readCSV("Central Java", "_dumpresult", "UCELLSETUP.csv"), "CELLID")
+	if got := cells["Huawei_Magelang"]; len(got) != 0 {
+		t.Errorf("Huawei_Magelang cells %q, want none with -archive-depth 0", got)
+	}
+}
+
+func TestNestedArchiveBudget(t *testing.T) {
+	var dump strings.Builder
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&dump, "ADD UCELL:CELLID=%d;\n", i*7919%100003)
+	}
+	inner := zipMembers(t, [2]string{"CFGMML.txt", dump.String()})
+	src := filepath.Join(t.TempDir(), "dump.zip")
+	if err := os.WriteFile(src, zipMembers(t, [2]string{"dump.zip", string(inner)}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// only the dump counts, not the copy of the zip around it
+	var read int
+	limits := extractLimits{MaxBytes: int64(dump.Len()), MaxDepth: 1}
+	err := walkArchive(src, limits, nil, nil, func(name string, r io.Reader) error {
+		b, err := io.ReadAll(r)
+		read += len(b)
+		return err
+	})
+	if err != nil || read != dump.Len() {
+		t.Errorf("read %d of %d bytes: %v", read, dump.Len(), err)
+	}
+
+	limits.MaxBytes = int64(len(inner)) - 1
+	err = walkArchive(src, limits, nil, nil, func(string, io.Reader) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "exceeds limit") {
+		t.Errorf("nested zip larger than the limit: %v", err)
+	}
+
+	// an exhausted budget refuses the next zip whatever it declares
+	w := &archiveWalker{budget: &extractBudget{limit: 10, written: 20}, fn: func(string, io.Reader) error { return nil }}
+	if err := w.walkZip(src, "", 0); err == nil || !strings.Contains(err.Error(), "exceeds limit") {
+		t.Errorf("overdrawn budget: %v", err)
+	}
+}
+
+func TestPipelineVerbModes(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+	p.serveDump("/bam/version_a/ftp/export_cfgmml/", "CFGMML-RNC1127-127.0.0.1-20211202083000", "CFGMML-RNC1127.txt", mod)
+	p.serveDump("/bam/version_b/ftp/export_cfgmml/", "CFGMML-RNC1198-127.0.0.1-20211202074512", "CFGMML-RNC1198.txt", mod)
+	p.writeConfigs("listrnc3g.json", append([]configs.Config(nil), testConfigs...))
+	result := filepath.Join(p.dir, "result", testDate, "3G", "National", "_dumpresult")
+
+	// each run adds its tables to the same result folder
+	p.run(runOptions{SkipDoubleSlash: true, VerbMode: verbModeAdd})
+	for _, name := range []string{"UCELL.csv", "SYS.csv"} {
+		if _, err := os.Stat(filepath.Join(result, name)); !os.IsNotExist(err) {
+			t.Errorf("add: %s written", name)
+		}
+	}
+	verbs := column(t, p.readCSV("National", "_dumpresult", "UCELLSETUP.csv"), "VERB")
+	if got := strings.Join(verbs["Huawei_Magelang"], ","); got != "ADD,ADD" {
+		t.Errorf("add: UCELLSETUP verbs %q, want ADD,ADD", got)
+	}
+
+	p.run(runOptions{SkipDoubleSlash: true, VerbMode: verbModeMerge})
+	verbs = column(t, p.readCSV("National", "_dumpresult", "UCELL.csv"), "VERB")
+	if got := strings.Join(verbs["Huawei_Magelang"], ","); got != "MOD" {
+		t.Errorf("merge: UCELL verbs %q, want MOD", got)
+	}
+	verbs = column(t, p.readCSV("National", "_dumpresult", "UCELLSETUP.csv"), "VERB")
+	if got := strings.Join(verbs["Huawei_Magelang"], ","); got != "ADD,ADD" {
+		t.Errorf("merge: UCELLSETUP verbs %q, want ADD,ADD", got)
+	}
+	if _, err := os.Stat(filepath.Join(result, "SYS.csv")); !os.IsNotExist(err) {
+		t.Errorf("merge: SET SYS written to SYS.csv")
+	}
+
+	p.run(runOptions{SkipDoubleSlash: true, VerbMode: verbModeSeparate})
+	for _, name := range []string{"ADD_UCELLSETUP.csv", "MOD_UCELL.csv", "SET_SYS.csv"} {
+		if _, err := os.Stat(filepath.Join(result, name)); err != nil {
+			t.Errorf("separate: %s", err)
+		}
+	}
+	// the verb is in the table name, not in a column
+	if got := strings.Join(p.readCSV("National", "_dumpresult", "SET_SYS.csv")[0], ","); got != "NE NAME,DUMP DATE,SYSOBJECTID,SYSDESC" {
+		t.Errorf("separate: SET_SYS header %s", got)
+	}
+}
+
+func TestPipelineLooseDumps(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+	p.serveDump("/bam/version_a/ftp/export_cfgmml/", "CFGMML-RNC1127-127.0.0.1-20211202083000", "CFGMML-RNC1127.txt", mod)
+	p.serveDump("/bam/version_b/ftp/export_cfgmml/", "CFGMML-RNC1198-127.0.0.1-20211202074512", "CFGMML-RNC1198.txt", mod)
+	cfgs := append([]configs.Config(nil), testConfigs...)
+	cfgs[1].NeName = "RNC1300"
+	p.writeConfigs("listrnc3g.json", cfgs)
+
+	// dumps dropped into the folder by hand, named by their content
+	folder := filepath.Join(p.dir, "result", testDate, "3G", "Central Java")
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{
+		"manual-export.txt": "//NE Name: RNC_Solo\nADD UCELLSETUP:CELLID=31001;\n",
+		"sys-only.txt":      "SET SYS:SYSOBJECTID=\"RNC1300\";\nADD UCELLSETUP:CELLID=41001;\n",
+		"anonymous.txt":     "ADD UCELLSETUP:CELLID=51001;\n",
+	} {
+		if err := os.WriteFile(filepath.Join(folder, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p.run(runOptions{SkipDoubleSlash: true})
+
+	cells := column(t, p.readCSV("Central Java", "_dumpresult", "UCELLSETUP.csv"), "CELLID")
+	if got := strings.Join(cells["RNC_Solo"], ","); got != "31001" {
+		t.Errorf("RNC_Solo cells %q, want 31001 from the header", got)
+	}
+	if got := strings.Join(cells["Huawei_Kudus"], ","); got != "21001,41001" {
+		t.Errorf("Huawei_Kudus cells %q, want 21001,41001 through the SYSOBJECTID alias", got)
+	}
+	if got := strings.Join(cells["Huawei_Magelang"], ","); got != "11001,11002" {
+		t.Errorf("Huawei_Magelang cells %q, want 11001,11002", got)
+	}
+	for ne, c := range cells {
+		for _, id := range c {
+			if id == "51001" {
+				t.Errorf("anonymous dump parsed as %s", ne)
+			}
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(folder, "anonymous.txt")); !os.IsNotExist(err) {
+		t.Errorf("anonymous.txt left in place")
+	}
+	reason, err := os.ReadFile(filepath.Join(folder, quarantineDir, "anonymous.txt.reason"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(reason), errUnknownNE.Error()) {
+		t.Errorf("quarantine reason %q", reason)
+	}
+}
+
+func TestPipelineLooseDumpsFileRules(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+	p.serveDump("/bam/version_a/ftp/export_cfgmml/", "CFGMML-RNC1127-127.0.0.1-20211202083000", "CFGMML-RNC1127.txt", mod)
+	p.serveDump("/bam/version_b/ftp/export_cfgmml/", "CFGMML-RNC1198-127.0.0.1-20211202074512", "CFGMML-RNC1198.txt", mod)
+	cfgs := append([]configs.Config(nil), testConfigs...)
+	// Kudus declares its prefix only in its file rules
+	cfgs[1].FilePrefix = ""
+	cfgs[1].Files = []configs.FileRule{{FilePrefix: "CFGMML-RNC1198-"}}
+	p.writeConfigs("listrnc3g.json", cfgs)
+
+	// a dump without NE name in it, named like a BAM export
+	folder := filepath.Join(p.dir, "result", testDate, "3G", "Central Java")
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(folder, "CFGMML-RNC1198-127.0.0.1-20211202090000.txt"), []byte("ADD UCELLSETUP:CELLID=21009;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p.run(runOptions{SkipDoubleSlash: true})
+
+	cells := column(t, p.readCSV("Central Java", "_dumpresult", "UCELLSETUP.csv"), "CELLID")
+	if got := strings.Join(cells["Huawei_Kudus"], ","); got != "21001,21009" {
+		t.Errorf("Huawei_Kudus cells %q, want 21001,21009 through the prefix of its file rule", got)
+	}
+}
+
+func TestPipelineParamRules(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+	p.serveDump("/bam/version_a/ftp/export_cfgmml/", "CFGMML-RNC1127-127.0.0.1-20211202083000", "CFGMML-RNC1127.txt", mod)
+	p.srv.AddFile("/bam/version_b/ftp/export_cfgmml/CFGMML-RNC1198-127.0.0.1-20211202074512.zip", zipMembers(t, [2]string{"CFGMML-RNC1198.txt",
+		"//NE Name: RNC_Kudus\n" +
+			"ADD UCELLALGO:CELLID=21001,ALGOSW=HO-INTER-1&LDR-0,NBRLIST=A&B,EXTSW=P-1&Q-0,OLDSW=X-1&Y,REMARK=on-1&off-0;\n"}), mod)
+	p.writeConfigs("listrnc3g.json", append([]configs.Config(nil), testConfigs...))
+
+	rules := configs.ParamRules{
+		Switch: []string{"UCELLSETUP.HSPAPLUSSWITCH", "ALGOSW", "OLDSW"},
+		Never:  []string{"REMARK"},
+	}
+	data, err := json.Marshal(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("paramrules.json", data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p.run(runOptions{SkipDoubleSlash: true, ParamRules: "paramrules.json"})
+
+	setup := p.readCSV("Central Java", "_dumpresult", "UCELLSETUP.csv")
+	if got := column(t, setup, "HSPAPLUSSWITCH")["Huawei_Magelang"][0]; got != "64QAM-1&MIMO-0&DC_HSDPA-1" {
+		t.Errorf("packed HSPAPLUSSWITCH %q", got)
+	}
+	if got := column(t, setup, "HSPAPLUSSWITCH_DC_HSDPA")["Huawei_Magelang"][0]; got != "1" {
+		t.Errorf("HSPAPLUSSWITCH_DC_HSDPA %q, want 1", got)
+	}
+
+	algo := p.readCSV("Central Java", "_dumpresult", "UCELLALGO.csv")
+	for col, want := range map[string]string{
+		"ALGOSW_HO-INTER": "1",
+		"ALGOSW_LDR":      "0",
+		"NBRLIST":         "A&B",
+		"EXTSW_Q":         "0",
+		"OLDSW_X":         "1",
+		"REMARK":          "on-1&off-0",
+	} {
+		if got := column(t, algo, col)["Huawei_Kudus"]; len(got) != 1 || got[0] != want {
+			t.Errorf("%s %q, want %s", col, got, want)
+		}
+	}
+	for _, h := range algo[0] {
+		if strings.HasPrefix(h, "REMARK_") || strings.HasPrefix(h, "NBRLIST_") {
+			t.Errorf("unexpected column %s", h)
+		}
+	}
+
+	kinds := make(map[string]string)
+	for _, r := range p.readCSV("parse_report.csv")[1:] {
+		if r[0] != "Huawei_Kudus" || r[2] != "UCELLALGO" || r[4] != "2" {
+			t.Errorf("report row %v, want Huawei_Kudus UCELLALGO line 2", r)
+		}
+		kinds[r[3]] = r[6]
+	}
+	want := map[string]string{"NBRLIST": reportUnlistedList, "EXTSW": reportUnlistedSwitch, "OLDSW": reportBadSwitchBit}
+	if len(kinds) != len(want) {
+		t.Errorf("report %v, want %v", kinds, want)
+	}
+	for param, kind := range want {
+		if kinds[param] != kind {
+			t.Errorf("report for %s %q, want %q", param, kinds[param], kind)
+		}
+	}
+}
+
+func TestPipelineHexValues(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+	p.serveDump("/bam/version_a/ftp/export_cfgmml/", "CFGMML-RNC1127-127.0.0.1-20211202083000", "CFGMML-RNC1127.txt", mod)
+	p.srv.AddFile("/bam/version_b/ftp/export_cfgmml/CFGMML-RNC1198-127.0.0.1-20211202074512.zip", zipMembers(t, [2]string{"CFGMML-RNC1198.txt",
+		"ADD UNODEB:ID=1,MASK=H'FFFFFFFFFFFFFFFFFFFF,LAC=H'2C01,BAD=H'2G,RAW=H'10;\n"}), mod)
+	p.writeConfigs("listrnc3g.json", append([]configs.Config(nil), testConfigs...))
+
+	data, err := json.Marshal(configs.ParamRules{Hex: []string{"RAW"}, HexBoth: []string{"UNODEB.LAC"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("paramrules.json", data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p.run(runOptions{SkipDoubleSlash: true, ParamRules: "paramrules.json"})
+
+	rows := p.readCSV("Central Java", "_dumpresult", "UNODEB.csv")
+	for col, want := range map[string]string{
+		"MASK":    "1208925819614629174706175",
+		"LAC":     "11265",
+		"LAC_HEX": "H'2C01",
+		"BAD":     "H'2G",
+		"RAW":     "H'10",
+	} {
+		if got := column(t, rows, col)["Huawei_Kudus"]; len(got) != 1 || got[0] != want {
+			t.Errorf("%s %q, want %s", col, got, want)
+		}
+	}
+	// only LAC of UNODEB gets both
+	if got := column(t, p.readCSV("Central Java", "_dumpresult", "UCELLSETUP.csv"), "LAC")["Huawei_Magelang"][0]; got != "11111" {
+		t.Errorf("UCELLSETUP LAC %q, want 11111", got)
+	}
+
+	var bad []string
+	for _, r := range p.readCSV("parse_report.csv")[1:] {
+		if r[6] == reportBadHex {
+			bad = append(bad, strings.Join([]string{r[0], r[2], r[3], r[4]}, " "))
+		}
+	}
+	if got := strings.Join(bad, ","); got != "Huawei_Kudus UNODEB BAD 1" {
+		t.Errorf("bad hex reported as %q", got)
+	}
+}
+
+func TestPipelineSchema(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+	p.serveDump("/bam/version_a/ftp/export_cfgmml/", "CFGMML-RNC1127-127.0.0.1-20211202083000", "CFGMML-RNC1127.txt", mod)
+	// a packed switch longer than a Text column
+	var bits []string
+	for i := 0; len(strings.Join(bits, "&")) <= 300; i++ {
+		bits = append(bits, fmt.Sprintf("HSPAPLUS_SWITCH_%02d-1", i))
+	}
+	p.srv.AddFile("/bam/version_b/ftp/export_cfgmml/CFGMML-RNC1198-127.0.0.1-20211202074512.zip", zipMembers(t, [2]string{"CFGMML-RNC1198.txt",
+		"ADD UNODEB:ID=1,NAME=\"NB 1\",IP=\"10.1.1.1\",SF=SF7,CODE=007,RATIO=1,BIG=3000000000,MASK=H'FF,HSPAPLUSSWITCH=" + strings.Join(bits, "&") + ";\n" +
+			"ADD UNODEB:ID=2,NAME=\"NB 2\",IP=\"10.1.1.2\",SF=SF8,CODE=008,RATIO=0.5,BIG=4000000000,HSPAPLUSSWITCH=HSPAPLUS_SWITCH_00-0;\n"}), mod)
+	p.writeConfigs("listrnc3g.json", append([]configs.Config(nil), testConfigs...))
+
+	data, err := json.Marshal(configs.ParamRules{Hex: []string{"MASK"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("paramrules.json", data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p.run(runOptions{SkipDoubleSlash: true, ParamRules: "paramrules.json"})
+
+	result := filepath.Join(p.dir, "result", testDate, "3G", "Central Java", "_dumpresult")
+	content, err := os.ReadFile(filepath.Join(result, "UNODEB.schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema configs.Schema
+	if err := json.Unmarshal(content, &schema); err != nil {
+		t.Fatal(err)
+	}
+	types := make(map[string]string)
+	for _, c := range schema.Columns {
+		types[c.Name] = c.Type
+	}
+	for col, want := range map[string]string{
+		"NE NAME":        configs.TypeEnum,
+		"DUMP DATE":      configs.TypeEnum,
+		"ID":             configs.TypeInteger,
+		"NAME":           configs.TypeText,
+		"IP":             configs.TypeIP,
+		"SF":             configs.TypeEnum,
+		"CODE":           configs.TypeEnum,
+		"RATIO":          configs.TypeDecimal,
+		"BIG":            configs.TypeDecimal,
+		"MASK":           configs.TypeHex,
+		"HSPAPLUSSWITCH": configs.TypeText,
+	} {
+		if types[col] != want {
+			t.Errorf("%s is %q, want %s", col, types[col], want)
+		}
+	}
+	if len(schema.Files) != 1 || schema.Files[0].File != "UNODEB.csv" || len(schema.Files[0].Columns) != len(schema.Columns) {
+		t.Errorf("files %+v", schema.Files)
+	}
+
+	// SF values are no longer quoted, the schema keeps them text
+	if got := column(t, p.readCSV("Central Java", "_dumpresult", "UNODEB.csv"), "SF")["Huawei_Kudus"]; strings.Join(got, ",") != "SF7,SF8" {
+		t.Errorf("SF %q", got)
+	}
+
+	ini, err := os.ReadFile(filepath.Join(result, "schema.ini"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"[UNODEB.csv]\r\n", "Col1=\"NE NAME\" Text Width 255\r\n", "\"ID\" Long\r\n", "\"NAME\" Memo\r\n", "\"RATIO\" Double\r\n", "\"HSPAPLUSSWITCH\" Memo\r\n", "[UCELLSETUP.csv]\r\n"} {
+		if !strings.Contains(string(ini), want) {
+			t.Errorf("schema.ini lacks %q", want)
+		}
+	}
+}
+
+func TestPipelineParamDict(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+	p.serveDump("/bam/version_a/ftp/export_cfgmml/", "CFGMML-RNC1127-127.0.0.1-20211202083000", "CFGMML-RNC1127.txt", mod)
+	p.writeConfigs("listrnc3g.json", append([]configs.Config(nil), testConfigs[0]))
+
+	dict := "MO,PARAMETER,FULL NAME,UNIT,VALUE RANGE,ENUM MEANINGS\n" +
+		"UCELLSETUP,CELLID,Cell ID,,0~65535,\n" +
+		",LAC,Location Area Code,,1~65533,\n" +
+		"UCELL,MAXTXPOWER,\"Max. Transmit Power, Cell\",0.1dBm,0~500,\n" +
+		"UCELLSETUP,HSPAPLUSSWITCH,HSPA+ Switch,,,\"64QAM:64QAM on HSDPA;MIMO:MIMO\"\n"
+	if err := os.WriteFile("paramdict.csv", []byte(dict), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p.run(runOptions{SkipDoubleSlash: true, ParamDict: "paramdict.csv", ParamAlias: true})
+
+	rows := p.readCSV("Central Java", "_dumpresult", paramDictTable+".csv")
+	var got []string
+	for _, r := range rows[1:] {
+		got = append(got, strings.Join(r[:4], "|"))
+	}
+	want := "UCELL|MAXTXPOWER|Max Transmit Power Cell|Max. Transmit Power, Cell," +
+		"UCELLSETUP|CELLID|Cell ID|Cell ID," +
+		"UCELLSETUP|HSPAPLUSSWITCH|HSPA+ Switch|HSPA+ Switch," +
+		"UCELLSETUP|LAC|Location Area Code|Location Area Code"
+	if strings.Join(got, ",") != want {
+		t.Errorf("%s rows %q", paramDictTable, got)
+	}
+	if rows[3][6] != "64QAM:64QAM on HSDPA;MIMO:MIMO" {
+		t.Errorf("enum meanings %q", rows[3][6])
+	}
+
+	// columns carry the full name, switch bits keep theirs
+	setup := p.readCSV("Central Java", "_dumpresult", "UCELLSETUP.csv")
+	if cells := column(t, setup, "Cell ID")["Huawei_Magelang"]; strings.Join(cells, ",") != "11001,11002" {
+		t.Errorf("Cell ID %q", cells)
+	}
+	column(t, setup, "HSPAPLUSSWITCH_MIMO")
+	column(t, p.readCSV("Central Java", "_dumpresult", "UCELL.csv"), "Max Transmit Power Cell")
+
+	var missing []string
+	for _, r := range p.readCSV("parse_report.csv")[1:] {
+		if r[6] == reportNotInDict {
+			missing = append(missing, r[2]+"."+r[3])
+		}
+	}
+	sort.Strings(missing)
+	if got := strings.Join(missing, ","); got != "UCELL.CELLID,UCELLSETUP.CELLNAME,UCELLSETUP.PSCRAMBCODE,UCELLSETUP.UARFCNDOWNLINK" {
+		t.Errorf("missing from dictionary %q", got)
+	}
+}
+
+func TestLoadParamDict(t *testing.T) {
+	dir := t.TempDir()
+	csvDict := filepath.Join(dir, "paramdict.csv")
+	jsonDict := filepath.Join(dir, "paramdict.json")
+	if err := os.WriteFile(csvDict, []byte("MO,PARAMETER,FULL NAME,UNIT,VALUE RANGE,ENUM MEANINGS\n"+
+		"UCELL,MAXTXPOWER,Max Transmit Power,0.1dBm,0~500,\n"+
+		",SWITCH,Switch,,,0:OFF;1:ON\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(jsonDict, []byte(`[
+		{"mo": "UCELL", "parameter": "MAXTXPOWER", "fullname": "Max Transmit Power", "unit": "0.1dBm", "range": "0~500"},
+		{"parameter": "SWITCH", "fullname": "Switch", "enums": "0:OFF;1:ON"}
+	]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// both formats describe the same fields
+	fromCSV, fromJSON := loadParamDict(csvDict), loadParamDict(jsonDict)
+	if len(fromCSV) != 2 || !reflect.DeepEqual(fromCSV, fromJSON) {
+		t.Errorf("csv %+v, json %+v", fromCSV, fromJSON)
+	}
+}
+
+func TestAliasColumn(t *testing.T) {
+	table := &configs.Table{
+		Header:    []string{"NE NAME", "DUMP DATE", "VERB", "CELLID", "LAC", "Cell ID"},
+		HeaderMap: map[string]int64{"NE NAME": 0, "DUMP DATE": 1, "VERB": 2, "CELLID": 3, "LAC": 4, "Cell ID": 5},
+	}
+	for _, tc := range []struct{ param, fullName, want string }{
+		{"LAC", "Location [Area] Code", "Location Area Code"},
+		{"CELLID", "cell id", "CELLID"},
+		{"MISSING", "Missing", "MISSING"},
+	} {
+		if got := aliasColumn(table, tc.param, tc.fullName); got != tc.want {
+			t.Errorf("%s: column %q, want %q", tc.param, got, tc.want)
+		}
+	}
+	// Header and HeaderMap agree on the new name
+	for i, h := range table.Header {
+		if idx, ok := table.HeaderMap[h]; !ok || idx != int64(i) {
+			t.Errorf("HeaderMap[%q] = %d, %v, want %d", h, idx, ok, i)
+		}
+	}
+	if _, ok := table.HeaderMap["LAC"]; ok || len(table.HeaderMap) != len(table.Header) {
+		t.Errorf("HeaderMap %v keeps the parameter name", table.HeaderMap)
+	}
+}
+
+func TestPipelineNationalPartTables(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+	p.serveDump("/bam/version_a/ftp/export_cfgmml/", "CFGMML-RNC1127-127.0.0.1-20211202083000", "CFGMML-RNC1127.txt", mod)
+	p.serveDump("/bam/version_b/ftp/export_cfgmml/", "CFGMML-RNC1198-127.0.0.1-20211202074512", "CFGMML-RNC1198.txt", mod)
+	p.writeConfigs("listrnc3g.json", append([]configs.Config(nil), testConfigs...))
+	if err := os.WriteFile("paramdict.csv", []byte("MO,PARAMETER,FULL NAME\nUCELLSETUP,CELLID,Cell ID\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p.run(runOptions{SkipDoubleSlash: true, ParamDict: "paramdict.csv"})
+
+	// rebuild the tables ExportAccess gets from their schemas
+	result := filepath.Join(p.dir, "result", testDate, "3G", "National", "_dumpresult")
+	files, err := filepath.Glob(filepath.Join(result, "*.schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := make(map[string]*configs.Table)
+	for _, f := range files {
+		content, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var schema configs.Schema
+		if err := json.Unmarshal(content, &schema); err != nil {
+			t.Fatal(err)
+		}
+		table := &configs.Table{Name: schema.Table}
+		for _, sf := range schema.Files {
+			table.ListFile = append(table.ListFile, sf.File)
+			table.TableName = append(table.TableName, sf.Table)
+			table.ListColumns = append(table.ListColumns, sf.Columns)
+		}
+		tables[schema.Table] = table
+	}
+	for _, name := range []string{paramDictTable, "UCELLSETUP"} {
+		if tables[name] == nil {
+			t.Fatalf("no %s table in National", name)
+		}
+	}
+
+	// every part gets the whole dictionary but only the rows of its NEs
+	for part, ne := range map[string]string{"1": "Huawei_Magelang", "2": "Huawei_Kudus"} {
+		listNE := []string{ne}
+		if src := textSource(tables[paramDictTable], 0, result, listNE); strings.Contains(src, "WHERE") {
+			t.Errorf("part %s: %s filtered by NE: %s", part, paramDictTable, src)
+		}
+		for name, table := range tables {
+			if name == paramDictTable {
+				continue
+			}
+			for i := range table.ListFile {
+				if src := textSource(table, i, result, listNE); !strings.HasSuffix(src, "WHERE file.[NE NAME] IN ('"+ne+"')") {
+					t.Errorf("part %s: %s not limited to %s: %s", part, table.ListFile[i], ne, src)
+				}
+			}
+		}
+	}
+}
+
+func TestPipelineCommentModes(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+	p.serveDump("/bam/version_a/ftp/export_cfgmml/", "CFGMML-RNC1127-127.0.0.1-20211202083000", "CFGMML-RNC1127.txt", mod)
+	p.writeConfigs("listrnc3g.json", append([]configs.Config(nil), testConfigs[0]))
+
+	for _, tc := range []struct {
+		opts      runOptions
+		cells     string
+		commented string
+	}{
+		{runOptions{SkipDoubleSlash: true}, "11001,11002", ""},
+		{runOptions{SkipDoubleSlash: false}, "11001,11002,11003", ""},
+		{runOptions{SkipDoubleSlash: true, CommentMode: commentStrip}, "11001,11002,11003", ""},
+		{runOptions{CommentMode: commentFlag}, "11001,11002,11003", "0,0,1"},
+	} {
+		p.run(tc.opts)
+
+		rows := p.readCSV("National", "_dumpresult", "UCELLSETUP.csv")
+		if got := strings.Join(column(t, rows, "CELLID")["Huawei_Magelang"], ","); got != tc.cells {
+			t.Errorf("%+v: cells %s, want %s", tc.opts, got, tc.cells)
+		}
+		if tc.commented == "" {
+			for _, h := range rows[0] {
+				if h == isCommentedColumn {
+					t.Errorf("%+v: unexpected %s column", tc.opts, isCommentedColumn)
+				}
+			}
+			continue
+		}
+		if rows[0][3] != isCommentedColumn {
+			t.Errorf("%+v: header %v", tc.opts, rows[0])
+		}
+		if got := strings.Join(column(t, rows, isCommentedColumn)["Huawei_Magelang"], ","); got != tc.commented {
+			t.Errorf("%+v: %s %s, want %s", tc.opts, isCommentedColumn, got, tc.commented)
+		}
+	}
+}
+
+func TestPipelineCSVLayout(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+
+	var wide strings.Builder
+	wide.WriteString("ADD UWIDE:ID=1")
+	for i := 1; i <= 600; i++ {
+		fmt.Fprintf(&wide, ",P%03d=%d", i, i)
+	}
+	wide.WriteString(";\n")
+	p.srv.AddFile("/bam/version_b/ftp/export_cfgmml/CFGMML-RNC1198-127.0.0.1-20211202074512.zip", zipMembers(t, [2]string{"CFGMML-RNC1198.txt",
+		"ADD UNODEB:ID=1,NAME=\"A,B\";\n" +
+			`ADD UNODEB:ID=2,NAME="say \"hi\"",EXTRA=1;` + "\n" +
+			wide.String()}), mod)
+	p.writeConfigs("listrnc3g.json", append([]configs.Config(nil), testConfigs[1]))
+
+	p.run(runOptions{SkipDoubleSlash: true})
+
+	// readCSV is strict, every row has the columns of the header
+	rows := p.readCSV("Central Java", "_dumpresult", "UNODEB.csv")
+	if got := column(t, rows, "NAME")["Huawei_Kudus"]; strings.Join(got, "|") != `A,B|say "hi"` {
+		t.Errorf("NAME %q", got)
+	}
+	if got := column(t, rows, "EXTRA")["Huawei_Kudus"]; strings.Join(got, "|") != "|1" {
+		t.Errorf("EXTRA %q", got)
+	}
+
+	// 3 keys, ID and 600 parameters over files of at most 255 columns
+	var params []string
+	for n := 1; n <= 3; n++ {
+		rows := p.readCSV("Central Java", "_dumpresult", fmt.Sprintf("UWIDE_%d.csv", n))
+		if len(rows) != 2 || len(rows[0]) > 255 {
+			t.Fatalf("UWIDE_%d: %d rows of %d columns", n, len(rows), len(rows[0]))
+		}
+		if got := strings.Join(rows[0][:3], ","); got != "NE NAME,DUMP DATE,VERB" || rows[1][0] != "Huawei_Kudus" {
+			t.Errorf("UWIDE_%d keys %s, %s", n, got, rows[1][0])
+		}
+		params = append(params, rows[0][3:]...)
+	}
+	if len(params) != 601 || params[0] != "ID" || params[600] != "P600" {
+		t.Errorf("split columns %d, from %s to %s", len(params), params[0], params[len(params)-1])
+	}
+	if _, err := os.Stat(filepath.Join(p.dir, "result", testDate, "3G", "Central Java", "_dumpresult", "UWIDE_4.csv")); !os.IsNotExist(err) {
+		t.Errorf("UWIDE_4.csv: %v", err)
+	}
+}
+
+func TestPipelineWorkers(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+	p.serveDump("/bam/version_a/ftp/export_cfgmml/", "CFGMML-RNC1127-127.0.0.1-20211202083000", "CFGMML-RNC1127.txt", mod)
+	p.serveDump("/bam/version_b/ftp/export_cfgmml/", "CFGMML-RNC1198-127.0.0.1-20211202074512", "CFGMML-RNC1198.txt", mod)
+	p.writeConfigs("listrnc3g.json", append([]configs.Config(nil), testConfigs...))
+
+	// every loose dump brings a column of its own
+	folder := filepath.Join(p.dir, "result", testDate, "3G", "Central Java")
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 12; i++ {
+		data := fmt.Sprintf("//NE Name: RNC_L%02d\nADD UCELLSETUP:CELLID=%d,P%02d=%d;\n", i, 60000+i, 11-i, i)
+		if err := os.WriteFile(filepath.Join(folder, fmt.Sprintf("loose-%02d.txt", i)), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result := filepath.Join(folder, "_dumpresult", "UCELLSETUP.csv")
+	p.run(runOptions{SkipDoubleSlash: true, Workers: 1})
+	want, err := os.ReadFile(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for run := 0; run < 3; run++ {
+		p.run(runOptions{SkipDoubleSlash: true, Workers: 8})
+		got, err := os.ReadFile(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("8 workers wrote\n%s\nwant\n%s", got, want)
+		}
+	}
+
+	rows := p.readCSV("Central Java", "_dumpresult", "UCELLSETUP.csv")
+	if got := strings.Join(rows[0][len(rows[0])-12:], ","); got != "P11,P10,P09,P08,P07,P06,P05,P04,P03,P02,P01,P00" {
+		t.Errorf("union columns %s", got)
+	}
+	if got := rows[len(rows)-1][0]; got != "RNC_L11" {
+		t.Errorf("last row of %s, want RNC_L11", got)
+	}
+}
+
+func TestPipelineParsesOnce(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+	p.serveDump("/bam/version_a/ftp/export_cfgmml/", "CFGMML-RNC1127-127.0.0.1-20211202083000", "CFGMML-RNC1127.txt", mod)
+	p.srv.AddFile("/bam/version_b/ftp/export_cfgmml/CFGMML-RNC1198-127.0.0.1-20211202074512.zip", zipMembers(t, [2]string{"CFGMML-RNC1198.txt",
+		"ADD UNODEB:ID=1,BAD=H'2G;\n"}), mod)
+	p.writeConfigs("listrnc3g.json", append([]configs.Config(nil), testConfigs...))
+
+	p.run(runOptions{SkipDoubleSlash: true, Workers: 4})
+
+	// the dump is in the region and the national folder, a second parse
+	// would count its problem twice
+	var counts []string
+	for _, r := range p.readCSV("parse_report.csv")[1:] {
+		if r[6] == reportBadHex {
+			counts = append(counts, r[5])
+		}
+	}
+	if got := strings.Join(counts, ","); got != "1" {
+		t.Errorf("bad hex counted %q, want 1", got)
+	}
+
+	for _, table := range []string{"UCELLSETUP.csv", "UNODEB.csv", dumpInfoTable + ".csv"} {
+		region := p.readCSV("Central Java", "_dumpresult", table)
+		national := p.readCSV("National", "_dumpresult", table)
+		if !reflect.DeepEqual(region, national) {
+			t.Errorf("%s differs\nregion   %q\nnational %q", table, region, national)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(p.dir, "result", testDate, "3G", fragmentDir)); !os.IsNotExist(err) {
+		t.Errorf("%s left behind", fragmentDir)
+	}
+}
+
+func TestPipelineDiagnostics(t *testing.T) {
+	p := newTestPipeline(t)
+	mod := time.Date(2021, 12, 2, 8, 30, 0, 0, time.UTC)
+	p.srv.AddFile("/bam/version_b/ftp/export_cfgmml/CFGMML-RNC1198-127.0.0.1-20211202074512.zip", zipMembers(t, [2]string{"CFGMML-RNC1198.txt",
+		"ADD UNODEB:ID=1;\n" +
+			"x\n" +
+			"ADD UNODEB:ID=2,ID=3,FLAG;\n" +
+			"garbage without colon\n"}), mod)
+	p.writeConfigs("listrnc3g.json", append([]configs.Config(nil), testConfigs[1]))
+
+	p.run(runOptions{SkipDoubleSlash: true})
+
+	var got []string
+	for _, r := range p.readCSV("parse_report.csv")[1:] {
+		got = append(got, strings.Join([]string{r[0], r[1], r[2], r[3], r[4], r[6]}, "|"))
+	}
+	source := "Huawei_Kudus_20211202.zip/CFGMML-RNC1198.txt"
+	want := []string{
+		"Huawei_Kudus|" + source + "|||2|" + mml.ProblemNotCommand,
+		"Huawei_Kudus|" + source + "|UNODEB|FLAG|3|" + mml.ProblemNoValue,
+		"Huawei_Kudus|" + source + "|UNODEB|ID|3|" + mml.ProblemDuplicate,
+		"Huawei_Kudus|" + source + "|||4|" + mml.ProblemNotCommand,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("report\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// the last of duplicate values is kept
+	if ids := column(t, p.readCSV("National", "_dumpresult", "UNODEB.csv"), "ID")["Huawei_Kudus"]; strings.Join(ids, ",") != "1,3" {
+		t.Errorf("ID %q, want 1,3", ids)
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	in := filepath.Join(testdataDir, "CFGMML-RNC1127.txt")
+
+	var out bytes.Buffer
+	if err := runParse([]string{"-in", in, "-mo", "ucellsetup", "-comment-mode", "flag"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d records, want 3:\n%s", len(lines), out.String())
+	}
+	var rec parseRecord
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.NeName != "RNC_Magelang" || rec.Line != 10 || rec.Verb != "ADD" || rec.MO != "UCELLSETUP" || rec.Params["CELLNAME"] != "MGL001_1" || rec.Params["LAC"] != "H'2B67" || rec.Source != "CFGMML-RNC1127.txt" {
+		t.Errorf("first record %+v", rec)
+	}
+	if err := json.Unmarshal([]byte(lines[2]), &rec); err != nil || !rec.Commented {
+		t.Errorf("last record %+v not commented: %v", rec, err)
+	}
+
+	out.Reset()
+	if err := runParse([]string{"-in", in, "-mo", "UCELLSETUP", "-comment-mode", "strip"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	lines = strings.Split(strings.TrimSpace(out.String()), "\n")
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &rec); err != nil || len(lines) != 3 || rec.Commented {
+		t.Errorf("strip gave %d records, last %+v: %v", len(lines), rec, err)
+	}
+
+	out.Reset()
+	if err := runParse([]string{"-in", in, "-format", "csv", "-mo", "UCELLSETUP"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(rows[0], ","); got != "NE NAME,SOURCE FILE,LINE,VERB,IS_COMMENTED,CELLID,CELLNAME,PSCRAMBCODE,UARFCNDOWNLINK,LAC,HSPAPLUSSWITCH" {
+		t.Errorf("header %s", got)
+	}
+	if cells := column(t, rows, "CELLID")["RNC_Magelang"]; strings.Join(cells, ",") != "11001,11002" {
+		t.Errorf("cells %q", cells)
+	}
+
+	if err := runParse([]string{"-in", in, "-format", "csv"}, &out); err == nil {
+		t.Error("csv without -mo accepted")
+	}
+	if err := runParse([]string{"-in", in, "-comment-mode", "drop"}, &out); err == nil {
+		t.Error("unknown comment mode accepted")
+	}
+}
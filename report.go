@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Kinds of parse report entries.
+const (
+	reportUnlistedSwitch = "UNLISTED SWITCH"
+	reportUnlistedList   = "UNLISTED LIST"
+	reportBadSwitchBit   = "BAD SWITCH BIT"
+	reportBadHex         = "BAD HEX"
+	reportNotInDict      = "NOT IN DICTIONARY"
+)
+
+// reportEntry is one kind of problem with one parameter of a dump, Line is
+// where it showed first and Count how often.
+type reportEntry struct {
+	NeName  string
+	Source  string
+	Table   string
+	Param   string
+	Kind    string
+	Message string
+	Line    int
+	Count   int
+}
+
+// parseReport collects what the parser could not handle cleanly, it is
+// written to parse_report.csv at the end of the run.
+type parseReport struct {
+	mu      sync.Mutex
+	entries map[reportKey]*reportEntry
+}
+
+type reportKey struct {
+	neName, source, table, param, kind string
+	line                               int
+}
+
+func newParseReport() *parseReport {
+	return &parseReport{entries: make(map[reportKey]*reportEntry)}
+}
+
+// add records a problem, repeats of the same kind for the same parameter
+// of a dump only count. A nil report ignores everything.
+func (p *parseReport) add(neName, source, table, param string, line int, kind, message string) {
+	p.record(reportKey{neName, source, table, param, kind, 0}, line, message)
+}
+
+// addLine records a problem of one line, like a skipped line, every line
+// gets an entry of its own.
+func (p *parseReport) addLine(neName, source, table, param string, line int, kind, message string) {
+	p.record(reportKey{neName, source, table, param, kind, line}, line, message)
+}
+
+func (p *parseReport) record(k reportKey, line int, message string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if e, ok := p.entries[k]; ok {
+		e.Count++
+		return
+	}
+	p.entries[k] = &reportEntry{NeName: k.neName, Source: k.source, Table: k.table, Param: k.param, Kind: k.kind, Message: message, Line: line, Count: 1}
+}
+
+func (p *parseReport) write(fpath string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entries := make([]*reportEntry, 0, len(p.entries))
+	for _, e := range p.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.NeName != b.NeName {
+			return a.NeName < b.NeName
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Table != b.Table {
+			return a.Table < b.Table
+		}
+		if a.Param != b.Param {
+			return a.Param < b.Param
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Message < b.Message
+	})
+
+	f, err := os.Create(fpath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"NE NAME", "SOURCE FILE", "TABLE", "PARAMETER", "LINE", "COUNT", "KIND", "MESSAGE"})
+	for _, e := range entries {
+		w.Write([]string{e.NeName, e.Source, e.Table, e.Param, strconv.Itoa(e.Line), strconv.Itoa(e.Count), e.Kind, e.Message})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func (p *parseReport) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.entries)
+}
+
+// summary counts the problems by kind, "BAD HEX: 2, NOT A COMMAND: 1".
+func (p *parseReport) summary() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	counts := make(map[string]int)
+	for _, e := range p.entries {
+		counts[e.Kind] += e.Count
+	}
+	kinds := make([]string, 0, len(counts))
+	for k := range counts {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	parts := make([]string, 0, len(kinds))
+	for _, k := range kinds {
+		parts = append(parts, k+": "+strconv.Itoa(counts[k]))
+	}
+	return strings.Join(parts, ", ")
+}